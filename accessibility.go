@@ -0,0 +1,190 @@
+package main
+
+import "image/color"
+
+type FlashingMode int
+
+const (
+	flashingFull FlashingMode = iota
+	flashingReduced
+	flashingOff
+)
+
+var flashingModeNames = []string{"Full", "Reduced", "Off"}
+
+func (m FlashingMode) String() string {
+	if int(m) < len(flashingModeNames) {
+		return flashingModeNames[m]
+	}
+	return "Unknown"
+}
+
+type PaletteID int
+
+const (
+	paletteDefault PaletteID = iota
+	paletteDeuteranopia
+	paletteProtanopia
+	paletteTritanopia
+)
+
+// Palette holds every colour that carries meaning during play, so the
+// drawing code never hard codes them.
+type Palette struct {
+	Name string
+
+	Ship          color.RGBA
+	Ship2         color.RGBA // player two's ship in co-op
+	Shield        color.RGBA
+	ShieldBlink   color.RGBA // alternates with Shield when the shield is running out
+	Enemy         color.RGBA
+	EnemyHit      color.RGBA
+	Invincible    color.RGBA // fill for enemies bullets can't hurt
+	Bullet        color.RGBA
+	PowerBullet   color.RGBA // bullets while invincible bullets is active
+	Anomaly       color.RGBA // overlay colour, alpha comes from the anomaly itself
+	Warning       color.RGBA
+	StrokeWidth   float32
+	HideStarfield bool
+}
+
+var palettes = []Palette{
+	paletteDefault: {
+		Name:        "Default",
+		Ship:        color.RGBA{255, 255, 255, 255},
+		Ship2:       color.RGBA{120, 220, 255, 255},
+		Shield:      color.RGBA{0, 255, 255, 180},
+		ShieldBlink: color.RGBA{255, 255, 255, 255},
+		Enemy:       color.RGBA{255, 255, 0, 255},
+		EnemyHit:    color.RGBA{255, 0, 0, 255},
+		Invincible:  color.RGBA{255, 0, 0, 255},
+		Bullet:      color.RGBA{0, 255, 0, 100},
+		PowerBullet: color.RGBA{255, 0, 255, 100},
+		Anomaly:     color.RGBA{255, 0, 0, 255},
+		Warning:     color.RGBA{255, 80, 80, 255},
+		StrokeWidth: 2,
+	},
+	// the colour blind palettes are built from the Okabe-Ito set
+	paletteDeuteranopia: {
+		Name:        "Deuteranopia",
+		Ship:        color.RGBA{255, 255, 255, 255},
+		Ship2:       color.RGBA{240, 228, 66, 255},
+		Shield:      color.RGBA{86, 180, 233, 200},
+		ShieldBlink: color.RGBA{255, 255, 255, 255},
+		Enemy:       color.RGBA{240, 228, 66, 255},
+		EnemyHit:    color.RGBA{0, 114, 178, 255},
+		Invincible:  color.RGBA{213, 94, 0, 255},
+		Bullet:      color.RGBA{86, 180, 233, 160},
+		PowerBullet: color.RGBA{230, 159, 0, 160},
+		Anomaly:     color.RGBA{213, 94, 0, 255},
+		Warning:     color.RGBA{230, 159, 0, 255},
+		StrokeWidth: 2,
+	},
+	paletteProtanopia: {
+		Name:        "Protanopia",
+		Ship:        color.RGBA{255, 255, 255, 255},
+		Ship2:       color.RGBA{240, 228, 66, 255},
+		Shield:      color.RGBA{86, 180, 233, 200},
+		ShieldBlink: color.RGBA{255, 255, 255, 255},
+		Enemy:       color.RGBA{240, 228, 66, 255},
+		EnemyHit:    color.RGBA{0, 114, 178, 255},
+		Invincible:  color.RGBA{204, 121, 167, 255},
+		Bullet:      color.RGBA{86, 180, 233, 160},
+		PowerBullet: color.RGBA{240, 228, 66, 160},
+		Anomaly:     color.RGBA{0, 114, 178, 255},
+		Warning:     color.RGBA{240, 228, 66, 255},
+		StrokeWidth: 2,
+	},
+	paletteTritanopia: {
+		Name:        "Tritanopia",
+		Ship:        color.RGBA{255, 255, 255, 255},
+		Ship2:       color.RGBA{86, 180, 233, 255},
+		Shield:      color.RGBA{0, 158, 115, 200},
+		ShieldBlink: color.RGBA{255, 255, 255, 255},
+		Enemy:       color.RGBA{255, 255, 255, 255},
+		EnemyHit:    color.RGBA{213, 94, 0, 255},
+		Invincible:  color.RGBA{204, 121, 167, 255},
+		Bullet:      color.RGBA{0, 158, 115, 160},
+		PowerBullet: color.RGBA{213, 94, 0, 160},
+		Anomaly:     color.RGBA{213, 94, 0, 255},
+		Warning:     color.RGBA{213, 94, 0, 255},
+		StrokeWidth: 2,
+	},
+}
+
+// highContrast replaces the starfield with black and uses a few saturated
+// colours with thicker lines. When it's on it replaces whichever palette is chosen.
+var highContrast = Palette{
+	Name:          "High contrast",
+	Ship:          color.RGBA{255, 255, 255, 255},
+	Ship2:         color.RGBA{255, 255, 0, 255},
+	Shield:        color.RGBA{0, 255, 255, 255},
+	ShieldBlink:   color.RGBA{255, 255, 255, 255},
+	Enemy:         color.RGBA{255, 255, 255, 255},
+	EnemyHit:      color.RGBA{255, 255, 0, 255},
+	Invincible:    color.RGBA{255, 0, 255, 255},
+	Bullet:        color.RGBA{0, 255, 0, 255},
+	PowerBullet:   color.RGBA{255, 255, 0, 255},
+	Anomaly:       color.RGBA{255, 0, 255, 255},
+	Warning:       color.RGBA{255, 255, 0, 255},
+	StrokeWidth:   3,
+	HideStarfield: true,
+}
+
+type Accessibility struct {
+	Flashing     FlashingMode `json:"flashing"`
+	Palette      PaletteID    `json:"palette"`
+	HighContrast bool         `json:"highContrast"`
+	ShapeCues    bool         `json:"shapeCues"` // draw patterns and labels so nothing relies on colour alone
+	ScreenShake  bool         `json:"screenShake"`
+}
+
+func defaultAccessibility() Accessibility {
+	return Accessibility{
+		Flashing:    flashingFull,
+		Palette:     paletteDefault,
+		ScreenShake: true,
+	}
+}
+
+func (a Accessibility) palette() Palette {
+	if a.HighContrast {
+		return highContrast
+	}
+	if int(a.Palette) >= 0 && int(a.Palette) < len(palettes) {
+		return palettes[a.Palette]
+	}
+	return palettes[paletteDefault]
+}
+
+// shipColour is the colour of a player's ship, player two gets their own so
+// co-op ships can be told apart.
+func (p Palette) shipColour(player int) color.RGBA {
+	if player == 1 {
+		return p.Ship2
+	}
+	return p.Ship
+}
+
+// blink reports whether something blinking every period frames is in its
+// "on" state. Reduced flashing slows the blink right down, and with flashing
+// off it just stays on.
+func (a Accessibility) blink(timer, period int) bool {
+	switch a.Flashing {
+	case flashingOff:
+		return true
+	case flashingReduced:
+		period *= 3
+	}
+	return (timer/period)%2 == 0
+}
+
+// applyAccessibility pushes the settings that live in other systems out to them.
+func (g *Game) applyAccessibility() {
+	g.effects.Config.ShakeEnabled = g.settings.Accessibility.ScreenShake
+	g.effects.Config.FlashEnabled = g.settings.Accessibility.Flashing != flashingOff
+	g.effects.Config.FlashIntensity = 1
+	if g.settings.Accessibility.Flashing == flashingReduced {
+		g.effects.Config.FlashIntensity = 0.35
+	}
+}
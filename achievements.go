@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"io/fs"
+	"log"
+	"math"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/font/basicfont"
+)
+
+const achievementsFile = "achievements.json"
+
+// toastFrames is how long an unlock stays on screen.
+const toastFrames = 180
+
+// achievement is one thing to earn. watch subscribes to whichever game
+// events it needs and calls earn with the player who did it.
+type achievement struct {
+	ID          string
+	Name        string
+	Description string
+	watch       func(bus *eventBus, run *achievementRun, earn func(p *Player))
+}
+
+var achievements = []achievement{
+	{
+		ID:          "edge-of-safety",
+		Name:        "Edge of Safety",
+		Description: "Survive an anomaly right at the edge of the safe zone",
+		watch: func(bus *eventBus, run *achievementRun, earn func(p *Player)) {
+			bus.anomalyStruck.subscribe(func(e AnomalyStruck) {
+				if e.Survived && e.Distance >= 0.85 && e.Distance <= 1 {
+					earn(e.Player)
+				}
+			})
+		},
+	},
+	{
+		ID:          "clean-sweep",
+		Name:        "Clean Sweep",
+		Description: "Clear 10 enemies with one bomb",
+		watch: func(bus *eventBus, run *achievementRun, earn func(p *Player)) {
+			bus.bombFinished.subscribe(func(e BombFinished) {
+				if e.Destroyed >= 10 {
+					earn(e.Player)
+				}
+			})
+		},
+	},
+	{
+		ID:          "no-safety-net",
+		Name:        "No Safety Net",
+		Description: "Reach 5000 points without picking up a shield",
+		watch: func(bus *eventBus, run *achievementRun, earn func(p *Player)) {
+			bus.scoreChanged.subscribe(func(e ScoreChanged) {
+				if e.Player.Score >= 5000 && !run.shielded[e.Player.ID] {
+					earn(e.Player)
+				}
+			})
+		},
+	},
+	{
+		ID:          "unstoppable",
+		Name:        "Unstoppable",
+		Description: "Bomb an invincible enemy while your bullets are invincible",
+		// bullets never get through an invincible enemy, only a bomb set to
+		// destroy them does
+		watch: func(bus *eventBus, run *achievementRun, earn func(p *Player)) {
+			bus.enemyDestroyed.subscribe(func(e EnemyDestroyed) {
+				if e.ByBomb && e.Enemy.IsInvincible && e.Player.Effects.has(effectInvincibleBullets) {
+					earn(e.Player)
+				}
+			})
+		},
+	},
+}
+
+// achievementRun is what the achievements need to remember about the run in progress.
+type achievementRun struct {
+	shielded [maxPlayers]bool // picked up a shield at some point
+}
+
+// Achievements is what's been unlocked and when, and the toasts still to show.
+type Achievements struct {
+	Unlocked map[string]time.Time `json:"unlocked"`
+
+	run        achievementRun
+	toasts     []string
+	toastTimer int
+}
+
+func loadAchievements() Achievements {
+	a := Achievements{Unlocked: map[string]time.Time{}}
+	data, err := readStorage(achievementsFile)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("reading achievements: %v", err)
+		}
+		return a
+	}
+	if err := json.Unmarshal(data, &a); err != nil {
+		log.Printf("reading achievements: %v", err)
+	}
+	if a.Unlocked == nil {
+		a.Unlocked = map[string]time.Time{}
+	}
+	return a
+}
+
+func (a *Achievements) save() {
+	data, err := json.Marshal(a)
+	if err == nil {
+		err = writeStorage(achievementsFile, data)
+	}
+	if err != nil {
+		log.Printf("saving achievements: %v", err)
+	}
+}
+
+// newRun forgets the last run. A continued run is treated as if it had
+// shields, there's no telling what happened before it was saved.
+func (a *Achievements) newRun(continued bool) {
+	a.run = achievementRun{}
+	if continued {
+		for i := range a.run.shielded {
+			a.run.shielded[i] = true
+		}
+	}
+}
+
+// subscribe hooks every achievement up to the game's events.
+func (a *Achievements) subscribe(g *Game) {
+	// a shield from a mystery powerup counts too, so look at the ship rather than the powerup
+	g.events.powerupCollected.subscribe(func(e PowerupCollected) {
+		if e.Player.Effects.has(effectShield) {
+			a.run.shielded[e.Player.ID] = true
+		}
+	})
+	for _, ach := range achievements {
+		ach.watch(&g.events, &a.run, func(p *Player) {
+			// achievements are only for whoever is at this machine
+			if n := g.network; n != nil && n.session != nil && p.ID != n.session.Player() {
+				return
+			}
+			a.unlock(ach)
+		})
+	}
+}
+
+func (a *Achievements) unlock(ach achievement) {
+	if _, ok := a.Unlocked[ach.ID]; ok {
+		return
+	}
+	a.Unlocked[ach.ID] = time.Now().UTC()
+	a.toasts = append(a.toasts, ach.Name)
+	a.save()
+}
+
+// updateToasts counts down the toast on screen, it runs every frame even
+// while paused so a toast doesn't hang around behind a menu.
+func (a *Achievements) updateToasts() {
+	if len(a.toasts) == 0 {
+		return
+	}
+	a.toastTimer++
+	if a.toastTimer >= toastFrames {
+		a.toasts = a.toasts[1:]
+		a.toastTimer = 0
+	}
+}
+
+func (a *Achievements) drawToast(screen *ebiten.Image) {
+	if len(a.toasts) == 0 {
+		return
+	}
+	msg := "Achievement unlocked: " + a.toasts[0]
+	bounds := text.BoundString(basicfont.Face7x13, msg)
+	w := float32(bounds.Dx() + 30)
+	x := (float32(screen.Bounds().Dx()) - w) / 2
+
+	// slide down from the top then back up at the end
+	slide := float32(math.Min(1, math.Min(float64(a.toastTimer), float64(toastFrames-a.toastTimer))/15))
+	y := -30 + 60*slide
+
+	vector.DrawFilledRect(screen, x, y, w, 26, color.RGBA{0, 0, 0, 200}, false)
+	vector.StrokeRect(screen, x, y, w, 26, 1, color.RGBA{255, 200, 0, 255}, false)
+	text.Draw(screen, msg, basicfont.Face7x13, int(x)+15, int(y)+17, color.RGBA{255, 200, 0, 255})
+}
+
+func newAchievementsMenu(g *Game) *Menu {
+	m := &Menu{title: fmt.Sprintf("ACHIEVEMENTS %d/%d", len(g.achievements.Unlocked), len(achievements))}
+	for _, ach := range achievements {
+		m.items = append(m.items, menuItem{label: func(g *Game) string {
+			mark := "[ ]"
+			if _, ok := g.achievements.Unlocked[ach.ID]; ok {
+				mark = "[x]"
+			}
+			return mark + " " + ach.Name + " - " + ach.Description
+		}})
+	}
+	m.items = append(m.items, backItem())
+	m.selected = len(m.items) - 1
+	return m
+}
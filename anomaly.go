@@ -1,8 +1,263 @@
 package main
 
-import "math/rand"
+import (
+	"encoding/json"
+	"image/color"
+	"math"
+	"math/rand/v2"
 
-func (a *Anomaly) Update() error {
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/font/basicfont"
+)
+
+const (
+	anomalyWarningFrames = 180 // frames of "incoming" before it arrives
+	anomalyFrames        = 360 // from being triggered to the strike
+
+	anomalyDriftSpeed = 1.5 // pixels a frame for a moving safe zone
+
+	shrinkStartRadius = 300
+	shrinkEndRadius   = 90
+
+	gravityCoreRadius = 80
+	gravityStrength   = 300 // the pull is this over the distance, in pixels a frame
+	gravityMaxPull    = 5
+)
+
+type anomalyKind int
+
+const (
+	anomalyClassic   anomalyKind = iota // one safe zone that stays put
+	anomalyMoving                       // one safe zone that drifts around
+	anomalyShrinking                    // a big safe zone that closes in
+	anomalyScattered                    // a few small safe zones
+	anomalyEMP                          // nobody can shoot until it's over, but it doesn't kill
+	anomalyGravity                      // a well that drags ships in, its core is what kills
+)
+
+// SafeZone is a circle the anomaly doesn't strike, for a gravity well it's the core.
+type SafeZone struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Radius float64 `json:"radius"`
+}
+
+// anomalyVariant is how one kind of anomaly behaves. place sets it up when
+// it's triggered, update runs every frame once it has arrived and draw is
+// what's on screen then. strike is how far a ship at x, y is from safety
+// when it hits, 1 being the edge, and anything without one doesn't kill.
+type anomalyVariant struct {
+	Telegraph     string // the warning while it's incoming
+	FromMilestone int    // the first 1000 point milestone it can turn up at
+	place         func(a *Anomaly, rng *rand.Rand)
+	update        func(a *Anomaly, g *Game)
+	draw          func(a *Anomaly, screen *ebiten.Image, fill color.RGBA, palette Palette)
+	strike        func(a *Anomaly, x, y float64) float64
+}
+
+var anomalyVariants = []anomalyVariant{
+	anomalyClassic: {
+		Telegraph:     "ANOMALY INCOMING!",
+		FromMilestone: 1,
+		place: func(a *Anomaly, rng *rand.Rand) {
+			a.Zones = []SafeZone{placeZone(rng, 150)}
+		},
+		draw:   drawSafeZones,
+		strike: nearestZone,
+	},
+	anomalyMoving: {
+		Telegraph:     "SAFE ZONE ON THE MOVE!",
+		FromMilestone: 2,
+		place: func(a *Anomaly, rng *rand.Rand) {
+			a.Zones = []SafeZone{placeZone(rng, 150)}
+			angle := rng.Float64() * 2 * math.Pi
+			a.DriftX = math.Cos(angle) * anomalyDriftSpeed
+			a.DriftY = math.Sin(angle) * anomalyDriftSpeed
+		},
+		update: func(a *Anomaly, g *Game) {
+			// bounce off the edges so the whole zone stays on screen
+			z := &a.Zones[0]
+			z.X += a.DriftX
+			z.Y += a.DriftY
+			if z.X < z.Radius || z.X > 1280-z.Radius {
+				a.DriftX = -a.DriftX
+				z.X = math.Max(z.Radius, math.Min(1280-z.Radius, z.X))
+			}
+			if z.Y < z.Radius || z.Y > 960-z.Radius {
+				a.DriftY = -a.DriftY
+				z.Y = math.Max(z.Radius, math.Min(960-z.Radius, z.Y))
+			}
+		},
+		draw:   drawSafeZones,
+		strike: nearestZone,
+	},
+	anomalyShrinking: {
+		Telegraph:     "SAFE ZONE COLLAPSING!",
+		FromMilestone: 3,
+		place: func(a *Anomaly, rng *rand.Rand) {
+			a.Zones = []SafeZone{placeZone(rng, shrinkStartRadius)}
+		},
+		update: func(a *Anomaly, g *Game) {
+			a.Zones[0].Radius = shrinkStartRadius + (shrinkEndRadius-shrinkStartRadius)*a.progress()
+		},
+		draw:   drawSafeZones,
+		strike: nearestZone,
+	},
+	anomalyScattered: {
+		Telegraph:     "SAFE ZONES SCATTERED!",
+		FromMilestone: 4,
+		place: func(a *Anomaly, rng *rand.Rand) {
+			a.Zones = nil
+			for len(a.Zones) < 3 {
+				// try to keep them apart, but don't try forever
+				z := placeZone(rng, 75)
+				for try := 0; try < 20 && tooClose(z, a.Zones); try++ {
+					z = placeZone(rng, 75)
+				}
+				a.Zones = append(a.Zones, z)
+			}
+		},
+		draw:   drawSafeZones,
+		strike: nearestZone,
+	},
+	anomalyEMP: {
+		Telegraph:     "EMP INCOMING!",
+		FromMilestone: 5,
+		place:         func(a *Anomaly, rng *rand.Rand) { a.Zones = nil },
+		draw: func(a *Anomaly, screen *ebiten.Image, fill color.RGBA, palette Palette) {
+			drawOverlayWithHoles(screen, color.RGBA{fill.R / 3, fill.G / 3, fill.B / 3, fill.A / 3})
+			msg := "WEAPONS OFFLINE"
+			bounds := text.BoundString(basicfont.Face7x13, msg)
+			text.Draw(screen, msg, basicfont.Face7x13, (screen.Bounds().Dx()-bounds.Dx())/2, 160, palette.Warning)
+		},
+	},
+	anomalyGravity: {
+		Telegraph:     "GRAVITY WELL FORMING!",
+		FromMilestone: 6,
+		place: func(a *Anomaly, rng *rand.Rand) {
+			a.Zones = []SafeZone{placeZone(rng, gravityCoreRadius)}
+		},
+		update: func(a *Anomaly, g *Game) {
+			well := a.Zones[0]
+			for _, p := range g.players {
+				if !p.Alive {
+					continue
+				}
+				dx := well.X - float64(p.Location.X)
+				dy := well.Y - float64(p.Location.Y)
+				d := math.Hypot(dx, dy)
+				if d < 1 {
+					continue
+				}
+				pull := math.Min(gravityMaxPull, gravityStrength/d)
+				p.Location.X += int(math.Round(dx / d * pull))
+				p.Location.Y += int(math.Round(dy / d * pull))
+			}
+		},
+		draw: func(a *Anomaly, screen *ebiten.Image, fill color.RGBA, palette Palette) {
+			well := a.Zones[0]
+			drawFilledCircle(screen, well.X, well.Y, well.Radius, fill)
+			vector.StrokeCircle(screen, float32(well.X), float32(well.Y), float32(well.Radius), palette.StrokeWidth, palette.Warning, true)
+
+			// rings falling in towards the core
+			for i := 0; i < 3; i++ {
+				phase := float64((a.fadeTimer+i*40)%120) / 120
+				r := well.Radius * (1 + 3*phase)
+				vector.StrokeCircle(screen, float32(well.X), float32(well.Y), float32(r), 1, palette.Anomaly, true)
+			}
+		},
+		strike: func(a *Anomaly, x, y float64) float64 {
+			// turned inside out so outside the core is safe and 1 is still the edge
+			well := a.Zones[0]
+			d := math.Hypot(x-well.X, y-well.Y)
+			if d == 0 {
+				return math.Inf(1)
+			}
+			return well.Radius / d
+		},
+	},
+}
+
+func (a *Anomaly) variant() anomalyVariant {
+	if int(a.Kind) >= 0 && int(a.Kind) < len(anomalyVariants) {
+		return anomalyVariants[a.Kind]
+	}
+	return anomalyVariants[anomalyClassic]
+}
+
+// placeZone picks somewhere for a zone with the whole circle on the screen.
+func placeZone(rng *rand.Rand, radius float64) SafeZone {
+	z := SafeZone{
+		X:      rng.Float64() * float64(1280),
+		Y:      rng.Float64() * float64(960),
+		Radius: radius,
+	}
+	z.X = math.Max(radius, math.Min(1280-radius, z.X))
+	z.Y = math.Max(radius, math.Min(960-radius, z.Y))
+	return z
+}
+
+func tooClose(z SafeZone, others []SafeZone) bool {
+	for _, o := range others {
+		if math.Hypot(z.X-o.X, z.Y-o.Y) < 2*(z.Radius+o.Radius) {
+			return true
+		}
+	}
+	return false
+}
+
+// nearestZone is the strike for anything with safe zones, being in any of them is enough.
+func nearestZone(a *Anomaly, x, y float64) float64 {
+	nearest := math.Inf(1)
+	for _, z := range a.Zones {
+		nearest = math.Min(nearest, math.Hypot(x-z.X, y-z.Y)/z.Radius)
+	}
+	return nearest
+}
+
+func drawSafeZones(a *Anomaly, screen *ebiten.Image, fill color.RGBA, palette Palette) {
+	drawOverlayWithHoles(screen, fill, a.Zones...)
+	for _, z := range a.Zones {
+		vector.StrokeCircle(screen, float32(z.X), float32(z.Y), float32(z.Radius), 1, palette.Warning, true)
+	}
+}
+
+// progress is how far it is from arriving (0) to striking (1).
+func (a *Anomaly) progress() float64 {
+	charge := float64(anomalyFrames - anomalyWarningFrames - 1)
+	return math.Max(0, math.Min(1, 1-float64(a.fadeTimer-1)/charge))
+}
+
+// lethal is whether the strike can kill anyone.
+func (a *Anomaly) lethal() bool {
+	return a.variant().strike != nil
+}
+
+// distance is how far x, y is from safety when it strikes, 1 being the edge.
+func (a *Anomaly) distance(x, y float64) float64 {
+	return a.variant().strike(a, x, y)
+}
+
+// jammed is true while an EMP has arrived and nobody can shoot.
+func (a *Anomaly) jammed() bool {
+	return a.IsActive && a.Incoming == 0 && a.Kind == anomalyEMP
+}
+
+// checkAnomalyMilestone sets off an anomaly at every new 1000 point
+// milestone, in co-op it's the team's score that counts.
+func (g *Game) checkAnomalyMilestone() {
+	score := g.totalScore()
+	if score/1000 <= g.Anomaly.lastAnomalyScore/1000 {
+		return
+	}
+	g.Anomaly.Activate(g.rngs[rngAnomaly], score/1000)
+	g.Anomaly.lastAnomalyScore = score
+	g.events.anomalyStarted.publish(AnomalyStarted{Anomaly: &g.Anomaly})
+}
+
+func (a *Anomaly) Update(g *Game) error {
 
 	if a.IsActive {
 
@@ -11,6 +266,10 @@ func (a *Anomaly) Update() error {
 			return nil
 		}
 
+		if update := a.variant().update; update != nil {
+			update(a, g)
+		}
+
 		// if we aren't at max alpha for anomaly, increase it
 		// if it is at max, we start the flash timer
 		if !a.flashing {
@@ -44,32 +303,27 @@ func (a *Anomaly) Update() error {
 	return nil
 }
 
-func (a *Anomaly) Activate() {
-	a.Incoming = 180 // frames until the anomaly is active
+// Activate triggers an anomaly, picking one of the variants the milestone has got up to.
+func (a *Anomaly) Activate(rng *rand.Rand, milestone int) {
+	var kinds []anomalyKind
+	for kind, v := range anomalyVariants {
+		if milestone >= v.FromMilestone {
+			kinds = append(kinds, anomalyKind(kind))
+		}
+	}
+
+	a.Incoming = anomalyWarningFrames // frames until the anomaly is active
 	a.IsActive = true
-	a.fadeTimer = 360
+	a.fadeTimer = anomalyFrames
 	a.fadeFlashTimer = 0
 	a.flashing = false
 	a.Alpha = 20
-	a.SafeRadius = 150
-
-	a.SafeX = rand.Float64() * float64(1280)
-	a.SafeY = rand.Float64() * float64(960)
-
-	// clamp safex and safey to be within the screen bounds
-	// so we get the whole safe circle on the screen
-	if a.SafeX < a.SafeRadius {
-		a.SafeX = a.SafeRadius
-	}
-	if a.SafeX > 1280-a.SafeRadius {
-		a.SafeX = 1280 - a.SafeRadius
-	}
-	if a.SafeY < a.SafeRadius {
-		a.SafeY = a.SafeRadius
-	}
-	if a.SafeY > 960-a.SafeRadius {
-		a.SafeY = 960 - a.SafeRadius
+	a.Kind = anomalyClassic
+	if len(kinds) > 0 {
+		a.Kind = kinds[rng.IntN(len(kinds))]
 	}
+	a.DriftX, a.DriftY = 0, 0
+	a.variant().place(a, rng)
 }
 
 func (a *Anomaly) Deactivate() {
@@ -79,7 +333,60 @@ func (a *Anomaly) Deactivate() {
 	a.fadeFlashTimer = 0
 	a.flashing = false
 	a.Alpha = 0
-	a.SafeX = 0
-	a.SafeY = 0
-	a.SafeRadius = 0
+	a.Kind = anomalyClassic
+	a.Zones = nil
+	a.DriftX, a.DriftY = 0, 0
+}
+
+// anomalyJSON mirrors Anomaly with every field exported, so a saved game
+// captures how far through its fade and flash the anomaly was.
+type anomalyJSON struct {
+	FadeTimer        int         `json:"fadeTimer"`
+	FadeFlashTimer   int         `json:"fadeFlashTimer"`
+	Flashing         bool        `json:"flashing"`
+	Kind             anomalyKind `json:"kind"`
+	Zones            []SafeZone  `json:"zones"`
+	DriftX           float64     `json:"driftX"`
+	DriftY           float64     `json:"driftY"`
+	Alpha            uint8       `json:"alpha"`
+	IsActive         bool        `json:"isActive"`
+	Incoming         int         `json:"incoming"`
+	LastAnomalyScore int         `json:"lastAnomalyScore"`
+}
+
+func (a Anomaly) MarshalJSON() ([]byte, error) {
+	return json.Marshal(anomalyJSON{
+		FadeTimer:        a.fadeTimer,
+		FadeFlashTimer:   a.fadeFlashTimer,
+		Flashing:         a.flashing,
+		Kind:             a.Kind,
+		Zones:            a.Zones,
+		DriftX:           a.DriftX,
+		DriftY:           a.DriftY,
+		Alpha:            a.Alpha,
+		IsActive:         a.IsActive,
+		Incoming:         a.Incoming,
+		LastAnomalyScore: a.lastAnomalyScore,
+	})
+}
+
+func (a *Anomaly) UnmarshalJSON(data []byte) error {
+	var j anomalyJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*a = Anomaly{
+		fadeTimer:        j.FadeTimer,
+		fadeFlashTimer:   j.FadeFlashTimer,
+		flashing:         j.Flashing,
+		Kind:             j.Kind,
+		Zones:            j.Zones,
+		DriftX:           j.DriftX,
+		DriftY:           j.DriftY,
+		Alpha:            j.Alpha,
+		IsActive:         j.IsActive,
+		Incoming:         j.Incoming,
+		lastAnomalyScore: j.LastAnomalyScore,
+	}
+	return nil
 }
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/stuartstein777/go-space-shooter/resources"
+	"github.com/stuartstein777/go-space-shooter/synth"
+)
+
+const sampleRate = 44100
+
+const (
+	sfxLaser     = "laser"
+	sfxExplosion = "explosion"
+	sfxBomb      = "bomb"
+	sfxPowerup   = "powerup"
+	sfxAnomaly   = "anomaly"
+	sfxDeath     = "death"
+)
+
+var audioContext *audio.Context
+var soundEffects = map[string][]byte{}
+var soundVolume = 1.0 // set from the settings
+
+// loadSounds renders every preset in resources/sfx.json into a PCM buffer
+// up front, so playing an effect is just handing a buffer to a new player.
+func loadSounds() {
+	audioContext = audio.NewContext(sampleRate)
+
+	presets, err := synth.ParsePresets(resources.SoundEffectsJSON)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, p := range presets {
+		soundEffects[p.Name] = synth.EncodeStereo16(synth.Render(p, sampleRate))
+	}
+}
+
+func playSound(name string) {
+	if audioContext == nil {
+		return
+	}
+	buf, ok := soundEffects[name]
+	if !ok {
+		return
+	}
+	player := audioContext.NewPlayerFromBytes(buf)
+	player.SetVolume(soundVolume)
+	player.Play()
+}
+
+// subscribeAudio plays the sound effects for what happens in the game.
+func subscribeAudio(bus *eventBus) {
+	bus.shotFired.subscribe(func(ShotFired) { playSound(sfxLaser) })
+	bus.enemyDestroyed.subscribe(func(e EnemyDestroyed) {
+		if !e.ByBomb { // the bomb has its own bang
+			playSound(sfxExplosion)
+		}
+	})
+	bus.bombDetonated.subscribe(func(BombDetonated) { playSound(sfxBomb) })
+	bus.powerupCollected.subscribe(func(PowerupCollected) { playSound(sfxPowerup) })
+	bus.anomalyStarted.subscribe(func(AnomalyStarted) { playSound(sfxAnomaly) })
+	bus.playerDamaged.subscribe(func(PlayerDamaged) { playSound(sfxExplosion) })
+	bus.playerDied.subscribe(func(PlayerDied) { playSound(sfxDeath) })
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	shockwaveSpeed     = 12.0 // pixels a frame the ring grows by
+	shockwaveKnockback = 8.0  // how fast it sends an enemy it can't destroy flying
+)
+
+// bombRule is what a bomb does to the enemies that are invincible, set in
+// the settings and fixed for the run like friendly fire.
+type bombRule int
+
+const (
+	bombPushesInvincible bombRule = iota // they're knocked away from the ship
+	bombIgnoresInvincible
+	bombDestroysInvincible
+)
+
+var bombRuleNames = []string{"Push away", "Ignore", "Destroy"}
+
+// Shockwave is a bomb going off, a ring that grows out from where the ship
+// was and destroys each enemy as it reaches it. The enemies don't split,
+// the bomb takes the lot.
+type Shockwave struct {
+	Owner     int     `json:"owner"`
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+	Radius    float64 `json:"radius"`
+	Destroyed int     `json:"destroyed"` // so far
+}
+
+// detonateBomb uses one of p's bombs, if it has one.
+func (g *Game) detonateBomb(p *Player) {
+	if p.Bombs == 0 {
+		return
+	}
+	p.Bombs--
+	g.flashTimer = 6 // just long enough to feel it, the ring is the show
+	g.shockwaves = append(g.shockwaves, &Shockwave{Owner: p.ID, X: float64(p.Location.X), Y: float64(p.Location.Y)})
+	g.events.bombDetonated.publish(BombDetonated{Player: p})
+}
+
+// updateShockwaves grows each ring and deals with every enemy its edge
+// passed this frame. A ring that's gone off the screen is finished.
+func (g *Game) updateShockwaves() {
+	screenWidth, screenHeight := g.Layout(0, 0)
+	kept := g.shockwaves[:0]
+	for _, s := range g.shockwaves {
+		p := g.players[s.Owner]
+		passed := s.Radius
+		s.Radius += shockwaveSpeed
+
+		for _, e := range g.enemies {
+			if !e.Active || e.HitTimer > 0 {
+				continue
+			}
+			dist := math.Hypot(e.X-s.X, e.Y-s.Y)
+			if edge := dist - e.Radius; edge > s.Radius || (passed > 0 && edge <= passed) {
+				continue
+			}
+			if (e.IsInvincible || g.enemiesInvincible()) && g.bombInvincible != bombDestroysInvincible {
+				if g.bombInvincible == bombPushesInvincible && dist > 0 {
+					e.VX = (e.X - s.X) / dist * shockwaveKnockback
+					e.VY = (e.Y - s.Y) / dist * shockwaveKnockback
+				}
+				continue
+			}
+			e.Active = false
+			s.Destroyed++
+			g.scoreKill(p, e, false, true)
+			g.events.enemyDestroyed.publish(EnemyDestroyed{Player: p, Enemy: e, ByBomb: true})
+		}
+
+		if s.Radius < math.Hypot(float64(screenWidth), float64(screenHeight)) {
+			kept = append(kept, s)
+		} else {
+			g.events.bombFinished.publish(BombFinished{Player: p, Destroyed: s.Destroyed})
+		}
+	}
+	g.shockwaves = kept
+
+	activeEnemies := g.enemies[:0]
+	for _, e := range g.enemies {
+		if e.Active {
+			activeEnemies = append(activeEnemies, e)
+		}
+	}
+	g.enemies = activeEnemies
+}
+
+func (g *Game) drawShockwaves(screen *ebiten.Image) {
+	palette := g.settings.Accessibility.palette()
+	screenWidth, screenHeight := g.Layout(0, 0)
+	reach := math.Hypot(float64(screenWidth), float64(screenHeight))
+	for _, s := range g.shockwaves {
+		// fade as it spreads out
+		c := palette.Shield
+		alpha := 1 - s.Radius/reach
+		col := color.RGBA{uint8(float64(c.R) * alpha), uint8(float64(c.G) * alpha), uint8(float64(c.B) * alpha), uint8(255 * alpha)}
+		vector.StrokeCircle(screen, float32(s.X), float32(s.Y), float32(s.Radius), palette.StrokeWidth*2, col, true)
+	}
+}
@@ -0,0 +1,41 @@
+// leaderboard-server is a reference server for the game's online
+// leaderboard. It keeps scores in a JSON file.
+//
+//	go run ./cmd/leaderboard-server -addr :8080 -file scores.json -key secret -verify ./space-shooter
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/stuartstein777/go-space-shooter/leaderboard"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	file := flag.String("file", "scores.json", "where to keep the scores")
+	key := flag.String("key", os.Getenv("LEADERBOARD_KEY"), "the key the game signs submissions with, or set LEADERBOARD_KEY")
+	verify := flag.String("verify", "", "path to the game, to check each replay with its verify command")
+	flag.Parse()
+
+	if *key == "" {
+		log.Fatal("a signing key is needed, pass -key or set LEADERBOARD_KEY")
+	}
+
+	store, err := leaderboard.OpenFileStore(*file)
+	if err != nil {
+		log.Fatalf("opening %s: %v", *file, err)
+	}
+
+	server := leaderboard.NewServer(store, []byte(*key))
+	if *verify != "" {
+		server.Verifier = leaderboard.CommandVerifier{Path: *verify}
+	} else {
+		log.Print("no -verify, scores are only checked for a valid signature")
+	}
+
+	log.Printf("leaderboard listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, server))
+}
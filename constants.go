@@ -15,10 +15,19 @@ const (
 	tileSize = 32
 )
 
+// inputBufferFrames is how early a shot or bomb can be pressed and still happen.
+const inputBufferFrames = 6
+
 const (
 	powerupShield            = 1
 	powerupBomb              = 2
 	powerupInvincibleBullets = 3
 	powerupFreezeEnemies     = 4
 	powerUpMystery           = 5
+	powerupMagnet            = 6
+	powerupTimeSlow          = 7
+	powerupDrone             = 8
+	powerupBombCapacity      = 9
+	powerupRepair            = 10
+	numPowerupTypes          = 11
 )
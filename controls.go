@@ -0,0 +1,106 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+type Action string
+
+const (
+	actionRotateLeft  Action = "rotateLeft"
+	actionRotateRight Action = "rotateRight"
+	actionThrust      Action = "thrust"
+	actionBrake       Action = "brake"
+	actionFire        Action = "fire"
+	actionBomb        Action = "bomb"
+)
+
+// actions is every rebindable action, in the order the controls menu lists them.
+var actions = []Action{
+	actionRotateLeft,
+	actionRotateRight,
+	actionThrust,
+	actionBrake,
+	actionFire,
+	actionBomb,
+}
+
+var actionNames = map[Action]string{
+	actionRotateLeft:  "Rotate left",
+	actionRotateRight: "Rotate right",
+	actionThrust:      "Accelerate",
+	actionBrake:       "Decelerate",
+	actionFire:        "Shoot",
+	actionBomb:        "Bomb",
+}
+
+type KeyBindings map[Action]ebiten.Key
+
+// defaultKeyBindings are WASD for player one and the arrow keys for player two,
+// far enough apart that two people can share a keyboard.
+func defaultKeyBindings(player int) KeyBindings {
+	if player == 1 {
+		return KeyBindings{
+			actionRotateLeft:  ebiten.KeyArrowLeft,
+			actionRotateRight: ebiten.KeyArrowRight,
+			actionThrust:      ebiten.KeyArrowUp,
+			actionBrake:       ebiten.KeyArrowDown,
+			actionFire:        ebiten.KeyEnter,
+			actionBomb:        ebiten.KeyShiftRight,
+		}
+	}
+	return KeyBindings{
+		actionRotateLeft:  ebiten.KeyA,
+		actionRotateRight: ebiten.KeyD,
+		actionThrust:      ebiten.KeyW,
+		actionBrake:       ebiten.KeyS,
+		actionFire:        ebiten.KeySpace,
+		actionBomb:        ebiten.KeyB,
+	}
+}
+
+// keyState is what an action's key is doing this frame.
+type keyState struct {
+	Pressed      bool
+	JustPressed  bool // it went down this frame
+	JustReleased bool // it came up this frame
+	Held         int  // frames it's been down for, 0 if it's up
+}
+
+func (k KeyBindings) state(a Action) keyState {
+	key, ok := k[a]
+	if !ok {
+		return keyState{}
+	}
+	return keyState{
+		Pressed:      ebiten.IsKeyPressed(key),
+		JustPressed:  inpututil.IsKeyJustPressed(key),
+		JustReleased: inpututil.IsKeyJustReleased(key),
+		Held:         inpututil.KeyPressDuration(key),
+	}
+}
+
+func (k KeyBindings) pressed(a Action) bool {
+	return k.state(a).Pressed
+}
+
+// bind assigns key to the action. If another action already used that key
+// it gets this action's old key, so nothing is ever left unbound.
+func (k KeyBindings) bind(a Action, key ebiten.Key) {
+	old := k[a]
+	for other, otherKey := range k {
+		if other != a && otherKey == key {
+			k[other] = old
+		}
+	}
+	k[a] = key
+}
+
+func (k KeyBindings) Clone() KeyBindings {
+	clone := make(KeyBindings, len(k))
+	for a, key := range k {
+		clone[a] = key
+	}
+	return clone
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"time"
+)
+
+// dailyFile remembers today's scored attempt at the daily challenge.
+const dailyFile = "daily.json"
+
+// the daily challenge is always on normal so everyone's playing the same game
+const dailyDifficulty = difficultyNormal
+
+// dailyRecord is the one scored attempt for a day. It's written as soon as
+// the run starts so quitting and starting again doesn't get another go.
+type dailyRecord struct {
+	Date     string `json:"date"`
+	Score    int    `json:"score"`
+	Finished bool   `json:"finished"`
+}
+
+// today is the daily challenge's date, it's the same day everywhere.
+func today() string {
+	return time.Now().UTC().Format(time.DateOnly)
+}
+
+// dailySeed is the seed everyone plays on the given date.
+func dailySeed(date string) (uint64, uint64) {
+	sum := sha256.Sum256([]byte("space-shooter daily " + date))
+	return binary.LittleEndian.Uint64(sum[:8]), binary.LittleEndian.Uint64(sum[8:16])
+}
+
+// checkDaily is whether cfg really is that day's challenge.
+func checkDaily(cfg RunConfig) error {
+	if _, err := time.Parse(time.DateOnly, cfg.Daily); err != nil {
+		return fmt.Errorf("bad daily challenge date %q", cfg.Daily)
+	}
+	seed1, seed2 := dailySeed(cfg.Daily)
+	switch {
+	case cfg.Seed1 != seed1 || cfg.Seed2 != seed2:
+		return errors.New("not the daily challenge's seed")
+	case cfg.Difficulty != dailyDifficulty:
+		return errors.New("daily challenges are played on normal")
+	}
+	return nil
+}
+
+func loadDaily() dailyRecord {
+	var r dailyRecord
+	data, err := readStorage(dailyFile)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("reading daily challenge: %v", err)
+		}
+		return r
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		log.Printf("reading daily challenge: %v", err)
+	}
+	return r
+}
+
+func saveDaily(r dailyRecord) {
+	data, err := json.Marshal(r)
+	if err == nil {
+		err = writeStorage(dailyFile, data)
+	}
+	if err != nil {
+		log.Printf("saving daily challenge: %v", err)
+	}
+}
+
+// startDaily begins today's challenge. Only the first go each day is scored,
+// after that it's practice, which plays the same but isn't submitted.
+func (g *Game) startDaily() {
+	date := today()
+	seed1, seed2 := dailySeed(date)
+	record := loadDaily()
+	practice := record.Date == date
+	if !practice {
+		saveDaily(dailyRecord{Date: date})
+	}
+
+	g.newRun(RunConfig{
+		Seed1:      seed1,
+		Seed2:      seed2,
+		Mode:       modeSurvival,
+		Players:    1,
+		Difficulty: dailyDifficulty,
+		Daily:      date,
+		Practice:   practice,
+	})
+}
+
+// finishDaily records the scored attempt's result, called from endRun.
+func (g *Game) finishDaily() {
+	if g.daily == "" || g.practice {
+		return
+	}
+	saveDaily(dailyRecord{Date: g.daily, Score: g.players[0].Score, Finished: true})
+}
+
+// dailyLabel is the title menu's daily challenge button.
+func dailyLabel() string {
+	record := loadDaily()
+	switch {
+	case record.Date != today():
+		return "Daily challenge"
+	case record.Finished:
+		return fmt.Sprintf("Daily practice (scored %d today)", record.Score)
+	default:
+		return "Daily practice"
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+// invulnerableFrames is how long a ship can't be hurt after losing a spare life.
+const invulnerableFrames = 120
+
+// Damage is something trying to hurt a ship. Everything that can goes
+// through damagePlayer, so shields, spare lives and so on work the same
+// whatever it was.
+type Damage struct {
+	Cause  deathCause
+	Bullet *Bullet // for diedToBullet
+	Enemy  *Enemy  // for diedToEnemy
+}
+
+type damageResult int
+
+const (
+	damageIgnored  damageResult = iota // it couldn't hurt the ship, e.g. it went through the shield
+	damageAbsorbed                     // the shield took it and is gone
+	damageLifeLost                     // a spare life took it, the ship carries on
+	damageFatal                        // the ship was destroyed
+)
+
+// shieldRules is what a shield does against each cause, it's the shield's
+// Protects. Enemies and bullets bounce off for as long as it lasts, an
+// anomaly strike uses it up, and the versus zone goes straight through it.
+var shieldRules = map[deathCause]damageResult{
+	diedToEnemy:   damageIgnored,
+	diedToBullet:  damageIgnored,
+	diedToAnomaly: damageAbsorbed,
+	diedToZone:    damageFatal,
+}
+
+// damagePlayer works out what d does to p and does it. Losing the last
+// ship in a run ends it, versus deals with its own deaths.
+func (g *Game) damagePlayer(p *Player, d Damage) damageResult {
+	result := g.resolveDamage(p, d)
+	switch result {
+	case damageAbsorbed:
+		_, kind, _ := protection(p.Effects, d.Cause)
+		p.Effects.remove(kind)
+	case damageLifeLost:
+		p.Lives--
+		p.Invulnerable = invulnerableFrames
+		p.breakCombo()
+	case damageFatal:
+		g.killPlayer(p, d.Cause, d.Bullet)
+	}
+	if result == damageAbsorbed || result == damageLifeLost {
+		g.events.playerDamaged.publish(PlayerDamaged{Player: p, Damage: d, Result: result})
+	}
+
+	if result == damageFatal && g.mode != modeVersus && g.alivePlayers() == 0 {
+		g.endRun()
+		g.Reset()
+	}
+	return result
+}
+
+// resolveDamage decides what d would do to p without changing anything.
+// Invulnerability comes first, then anything the ship's effects protect it
+// from, then its spare lives.
+func (g *Game) resolveDamage(p *Player, d Damage) damageResult {
+	if !p.Alive || p.Invulnerable > 0 {
+		return damageIgnored
+	}
+	if result, _, ok := protection(p.Effects, d.Cause); ok {
+		return result
+	}
+	if p.Lives > 0 {
+		return damageLifeLost
+	}
+	return damageFatal
+}
+
+// protection is the best any of fx can do against cause, going by their
+// Protects, and which effect does it. Something that ignores the damage
+// beats something that's used up by it.
+func protection(fx ActiveEffects, cause deathCause) (damageResult, effectKind, bool) {
+	best, by, found := damageFatal, effectKind(0), false
+	for _, e := range fx {
+		result, ok := effectDefs[e.Kind].Protects[cause]
+		if !ok || result == damageFatal {
+			continue
+		}
+		if !found || result < best {
+			best, by, found = result, e.Kind, true
+		}
+	}
+	return best, by, found
+}
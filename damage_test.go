@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDamagePlayer(t *testing.T) {
+	type cause struct {
+		name    string
+		mode    GameMode
+		players int
+		damage  Damage
+		shield  damageResult // what a shield does about it, damageFatal if nothing
+	}
+	causes := []cause{
+		{"enemy", modeSurvival, 1, Damage{Cause: diedToEnemy, Enemy: &Enemy{Active: true}}, damageIgnored},
+		{"enemy bullet", modeVersus, 2, Damage{Cause: diedToBullet, Bullet: &Bullet{Active: true, Owner: 1}}, damageIgnored},
+		{"friendly fire", modeSurvival, 2, Damage{Cause: diedToBullet, Bullet: &Bullet{Active: true, Owner: 1}}, damageIgnored},
+		{"anomaly", modeSurvival, 1, Damage{Cause: diedToAnomaly}, damageAbsorbed},
+		{"zone", modeVersus, 2, Damage{Cause: diedToZone}, damageFatal},
+	}
+
+	for _, c := range causes {
+		for _, shield := range []bool{false, true} {
+			for _, lives := range []int{0, 1, 3} {
+				for _, invulnerable := range []bool{false, true} {
+					name := fmt.Sprintf("%s/shield=%v/lives=%d/invulnerable=%v", c.name, shield, lives, invulnerable)
+					t.Run(name, func(t *testing.T) {
+						g := &Game{headless: true, settings: defaultSettings()}
+						cfg := g.newRunConfig(c.mode, c.players)
+						cfg.FriendlyFire = true
+						g.newRun(cfg)
+						p := g.players[0]
+						p.Lives = lives
+						p.Invulnerable = 0
+						if invulnerable {
+							p.Invulnerable = 10
+						}
+						p.Effects.remove(effectShield) // versus starts ships with one
+						if shield {
+							p.Effects.add(effectShield, 300)
+						}
+
+						var want damageResult
+						switch {
+						case invulnerable:
+							want = damageIgnored
+						case shield && c.shield != damageFatal:
+							want = c.shield
+						case lives > 0:
+							want = damageLifeLost
+						default:
+							want = damageFatal
+						}
+
+						got := g.damagePlayer(p, c.damage)
+						if got != want {
+							t.Fatalf("got result %d, want %d", got, want)
+						}
+
+						wantLives := lives
+						if want == damageLifeLost {
+							wantLives--
+						}
+						if p.Lives != wantLives {
+							t.Errorf("lives %d, want %d", p.Lives, wantLives)
+						}
+						if p.Effects.has(effectShield) != (shield && want != damageAbsorbed) {
+							t.Errorf("shield up %v after %d", p.Effects.has(effectShield), want)
+						}
+						switch want {
+						case damageLifeLost:
+							if p.Invulnerable != invulnerableFrames {
+								t.Errorf("respawned with %d frames of invulnerability, want %d", p.Invulnerable, invulnerableFrames)
+							}
+						case damageIgnored:
+							if invulnerable && p.Invulnerable != 10 {
+								t.Errorf("invulnerability changed to %d", p.Invulnerable)
+							}
+						}
+						if p.Alive != (want != damageFatal) {
+							t.Errorf("alive %v after %d", p.Alive, want)
+						}
+
+						// only the last ship going ends the run, and versus
+						// sorts out its own rounds
+						gameOver := want == damageFatal && c.mode != modeVersus && c.players == 1
+						if g.showSplash != gameOver || (g.finalState != "") != gameOver {
+							t.Errorf("game over %v (final state %q), want %v", g.showSplash, g.finalState, gameOver)
+						}
+					})
+				}
+			}
+		}
+	}
+}
+
+func TestDamageLastShipInCoopEndsTheRun(t *testing.T) {
+	g := &Game{headless: true, settings: defaultSettings()}
+	cfg := g.newRunConfig(modeSurvival, 2)
+	cfg.FriendlyFire = true
+	g.newRun(cfg)
+	for _, p := range g.players {
+		p.Lives = 0
+		p.Invulnerable = 0
+	}
+
+	bullet := &Bullet{Active: true, Owner: 1}
+	if got := g.damagePlayer(g.players[0], Damage{Cause: diedToBullet, Bullet: bullet}); got != damageFatal {
+		t.Fatalf("got result %d, want fatal", got)
+	}
+	if g.showSplash {
+		t.Fatalf("the run ended with a ship still flying")
+	}
+	if got := g.damagePlayer(g.players[1], Damage{Cause: diedToEnemy, Enemy: &Enemy{Active: true}}); got != damageFatal {
+		t.Fatalf("got result %d, want fatal", got)
+	}
+	if !g.showSplash || g.finalState == "" {
+		t.Fatalf("the run didn't end with both ships gone")
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	droneOrbitRadius = 45.0
+	droneOrbitSpeed  = 0.05 // radians a frame
+	droneCooldown    = 40   // frames between shots
+	droneRange       = 400.0
+)
+
+// dronePositions is where each of p's drones is, spread evenly round the ship.
+func (p *Player) dronePositions() [][2]float64 {
+	n := p.Effects.stacks(effectDrone)
+	positions := make([][2]float64, n)
+	for i := range positions {
+		angle := float64(p.FramesAlive)*droneOrbitSpeed + float64(i)*2*math.Pi/float64(n)
+		positions[i] = [2]float64{
+			float64(p.Location.X) + droneOrbitRadius*math.Cos(angle),
+			float64(p.Location.Y) + droneOrbitRadius*math.Sin(angle),
+		}
+	}
+	return positions
+}
+
+// fireDrones has each drone shoot at the nearest enemy it can hurt, once they've cooled down.
+func (g *Game) fireDrones() {
+	if g.Anomaly.jammed() {
+		return
+	}
+	for _, p := range g.players {
+		if !p.Alive || !p.Effects.has(effectDrone) {
+			continue
+		}
+		if p.DroneCooldown > 0 {
+			p.DroneCooldown--
+			continue
+		}
+		for _, d := range p.dronePositions() {
+			target := g.nearestEnemy(d[0], d[1], droneRange)
+			if target == nil {
+				continue
+			}
+			dist := math.Max(1, math.Hypot(target.X-d[0], target.Y-d[1]))
+			bullet := &Bullet{
+				X:      d[0],
+				Y:      d[1],
+				VX:     (target.X - d[0]) / dist * bulletSpeed,
+				VY:     (target.Y - d[1]) / dist * bulletSpeed,
+				Active: true,
+				Owner:  p.ID,
+			}
+			g.bullets = append(g.bullets, bullet)
+			g.events.shotFired.publish(ShotFired{Player: p, Bullet: bullet})
+			p.DroneCooldown = droneCooldown
+		}
+	}
+}
+
+// nearestEnemy is the closest enemy to x, y within range that a bullet would hurt.
+func (g *Game) nearestEnemy(x, y, within float64) *Enemy {
+	var nearest *Enemy
+	for _, e := range g.enemies {
+		if !e.Active || e.HitTimer > 0 || e.IsInvincible || g.enemiesInvincible() {
+			continue
+		}
+		if d := math.Hypot(e.X-x, e.Y-y); d < within {
+			nearest, within = e, d
+		}
+	}
+	return nearest
+}
+
+func drawDrones(screen *ebiten.Image, p *Player, colour color.Color, width float32) {
+	for _, d := range p.dronePositions() {
+		vector.StrokeCircle(screen, float32(d[0]), float32(d[1]), 6, width, colour, true)
+		vector.DrawFilledCircle(screen, float32(d[0]), float32(d[1]), 2, colour, true)
+	}
+}
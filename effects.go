@@ -0,0 +1,212 @@
+package main
+
+import (
+	"image/color"
+	"math/rand/v2"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+type EffectsConfig struct {
+	ShakeEnabled    bool
+	FlashEnabled    bool
+	FlashIntensity  float64 // scales the opacity of tint flashes
+	ShakeScale      float64 // multiplies every shake, 1 is normal
+	MaxShakeOffset  float64 // pixels at full trauma
+	ShakeDecay      float64 // trauma lost per frame
+	HitStopFrames   int     // frames the game freezes on a large kill
+	SlowMotionScale float64 // fraction of frames simulated during slow motion, 0.5 is half speed
+}
+
+func defaultEffectsConfig() EffectsConfig {
+	return EffectsConfig{
+		ShakeEnabled:    true,
+		FlashEnabled:    true,
+		FlashIntensity:  1,
+		ShakeScale:      1,
+		MaxShakeOffset:  16,
+		ShakeDecay:      0.03,
+		HitStopFrames:   4,
+		SlowMotionScale: 0.5,
+	}
+}
+
+// Effects is the camera layer that sits between the game and the screen.
+// None of this changes the outcome of the game, it just makes things feel
+// punchier, so everything here can be switched off.
+type Effects struct {
+	Config EffectsConfig
+
+	trauma float64 // 0..1, shake is proportional to trauma squared
+
+	hitStop int
+
+	slowMotionTimer int
+	slowMotionAccum float64
+
+	tint         color.RGBA
+	tintTimer    int
+	tintDuration int
+
+	offscreen        *ebiten.Image
+	offsetX, offsetY float64
+}
+
+// Shake adds trauma, amount is 0..1 where 1 is the biggest shake we do.
+func (fx *Effects) Shake(amount float64) {
+	if !fx.Config.ShakeEnabled {
+		return
+	}
+	fx.trauma += amount * fx.Config.ShakeScale
+	if fx.trauma > 1 {
+		fx.trauma = 1
+	}
+}
+
+// HitStop freezes the game for a few frames, used for big kills.
+func (fx *Effects) HitStop() {
+	if fx.Config.HitStopFrames > fx.hitStop {
+		fx.hitStop = fx.Config.HitStopFrames
+	}
+}
+
+func (fx *Effects) SlowMotion(frames int) {
+	if frames > fx.slowMotionTimer {
+		fx.slowMotionTimer = frames
+	}
+}
+
+// Flash tints the whole screen with a colour that fades out over the given frames.
+func (fx *Effects) Flash(c color.RGBA, frames int) {
+	if !fx.Config.FlashEnabled {
+		return
+	}
+	fx.tint = c
+	fx.tintTimer = frames
+	fx.tintDuration = frames
+}
+
+func (fx *Effects) Reset() {
+	fx.trauma = 0
+	fx.hitStop = 0
+	fx.slowMotionTimer = 0
+	fx.slowMotionAccum = 0
+	fx.tintTimer = 0
+}
+
+// Update ticks the effects and reports whether the game should simulate this frame.
+// Hit-stop skips frames entirely, slow motion skips some of them.
+func (fx *Effects) Update() bool {
+	if fx.trauma > 0 {
+		fx.trauma -= fx.Config.ShakeDecay
+		if fx.trauma < 0 {
+			fx.trauma = 0
+		}
+	}
+
+	if fx.tintTimer > 0 {
+		fx.tintTimer--
+	}
+
+	if fx.hitStop > 0 {
+		fx.hitStop--
+		return false
+	}
+
+	if fx.slowMotionTimer > 0 {
+		fx.slowMotionTimer--
+		fx.slowMotionAccum += fx.Config.SlowMotionScale
+		if fx.slowMotionAccum < 1 {
+			return false
+		}
+		fx.slowMotionAccum--
+	}
+
+	return true
+}
+
+func (fx *Effects) shakeOffset() (float64, float64) {
+	if !fx.Config.ShakeEnabled || fx.trauma == 0 {
+		return 0, 0
+	}
+	amount := fx.trauma * fx.trauma * fx.Config.MaxShakeOffset
+	return (rand.Float64()*2 - 1) * amount, (rand.Float64()*2 - 1) * amount
+}
+
+// Begin returns the image the game should draw into this frame.
+// When the screen is shaking that's an offscreen image which End then
+// draws onto the screen at an offset.
+func (fx *Effects) Begin(screen *ebiten.Image) *ebiten.Image {
+	fx.offsetX, fx.offsetY = fx.shakeOffset()
+	if fx.offsetX == 0 && fx.offsetY == 0 {
+		return screen
+	}
+	w, h := screen.Bounds().Dx(), screen.Bounds().Dy()
+	if fx.offscreen == nil || fx.offscreen.Bounds().Dx() != w || fx.offscreen.Bounds().Dy() != h {
+		fx.offscreen = ebiten.NewImage(w, h)
+	}
+	fx.offscreen.Clear()
+	return fx.offscreen
+}
+
+func (fx *Effects) End(screen, world *ebiten.Image) {
+	if world != screen {
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(fx.offsetX, fx.offsetY)
+		screen.DrawImage(world, op)
+	}
+
+	if fx.tintTimer > 0 && fx.Config.FlashEnabled {
+		alpha := float32(fx.tint.A) / 255 * float32(fx.tintTimer) / float32(fx.tintDuration) * float32(fx.Config.FlashIntensity)
+		r, g, b, _ := colorToFloats(fx.tint)
+		w, h := screen.Bounds().Dx(), screen.Bounds().Dy()
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(float64(w), float64(h))
+		op.ColorScale.Scale(r*alpha, g*alpha, b*alpha, alpha)
+		screen.DrawImage(whiteImg, op)
+	}
+}
+
+// subscribeEffects hooks the particles and screen effects up to the game's events.
+func (g *Game) subscribeEffects() {
+	bus := &g.events
+	bus.enemyHit.subscribe(func(e EnemyHit) {
+		g.particles.EmitSparks(e.X, e.Y)
+	})
+	bus.enemyDestroyed.subscribe(func(e EnemyDestroyed) {
+		g.particles.EmitExplosion(e.Enemy.X, e.Enemy.Y, e.Enemy.Radius)
+		if e.ByBomb {
+			return // the bomb shakes the screen once for the lot
+		}
+		g.effects.Shake(e.Enemy.Radius / 200)
+		if e.Enemy.Radius >= 40 {
+			g.effects.HitStop()
+		}
+	})
+	bus.bombDetonated.subscribe(func(e BombDetonated) {
+		g.effects.Shake(1)
+		g.effects.SlowMotion(45)
+		g.effects.Flash(color.RGBA{255, 255, 255, 255}, 20)
+		g.particles.EmitShockwave(float64(e.Player.Location.X), float64(e.Player.Location.Y))
+	})
+	bus.powerupCollected.subscribe(func(e PowerupCollected) {
+		g.particles.EmitPickup(e.Powerup.X, e.Powerup.Y, e.Powerup.Type)
+		g.effects.Flash(powerupInfo(e.Powerup.Type).Colour, 10)
+	})
+	bus.playerDamaged.subscribe(func(e PlayerDamaged) {
+		if e.Damage.Bullet != nil {
+			g.particles.EmitSparks(e.Damage.Bullet.X, e.Damage.Bullet.Y)
+		}
+		g.particles.EmitExplosion(float64(e.Player.Location.X), float64(e.Player.Location.Y), 15)
+		g.effects.Shake(0.5)
+		g.effects.Flash(color.RGBA{255, 0, 0, 100}, 15)
+	})
+	bus.playerDied.subscribe(func(e PlayerDied) {
+		if e.Bullet != nil {
+			g.particles.EmitSparks(e.Bullet.X, e.Bullet.Y)
+		}
+		g.particles.EmitExplosion(float64(e.Player.Location.X), float64(e.Player.Location.Y), 40)
+		g.effects.Shake(0.8)
+		g.effects.Flash(color.RGBA{255, 0, 0, 160}, 30)
+	})
+}
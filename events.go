@@ -0,0 +1,146 @@
+package main
+
+// The simulation publishes what happens on g.events and everything that only
+// reacts to it (sound, particles, screen effects, achievements, stats) subscribes,
+// so none of that has to live in the collision code. Subscribers must never
+// change the simulation, replays and network games rely on it depending on
+// nothing but the inputs. A replay being verified has no subscribers at all.
+
+// topic is the subscribers to one type of event, called in the order they subscribed.
+type topic[E any] struct {
+	handlers []func(E)
+}
+
+func (t *topic[E]) subscribe(fn func(E)) {
+	t.handlers = append(t.handlers, fn)
+}
+
+func (t *topic[E]) publish(ev E) {
+	for _, fn := range t.handlers {
+		fn(ev)
+	}
+}
+
+type eventBus struct {
+	shotFired        topic[ShotFired]
+	enemyHit         topic[EnemyHit]
+	enemyDestroyed   topic[EnemyDestroyed]
+	powerupCollected topic[PowerupCollected]
+	effectStarted    topic[EffectStarted]
+	bombDetonated    topic[BombDetonated]
+	bombFinished     topic[BombFinished]
+	anomalyStarted   topic[AnomalyStarted]
+	anomalyStruck    topic[AnomalyStruck]
+	playerDamaged    topic[PlayerDamaged]
+	playerDied       topic[PlayerDied]
+	scoreChanged     topic[ScoreChanged]
+}
+
+type ShotFired struct {
+	Player *Player
+	Bullet *Bullet
+}
+
+// EnemyHit is a bullet reaching an enemy. Blocked is when the enemy is
+// invincible and the bullet just sparks off it.
+type EnemyHit struct {
+	Player  *Player // whose bullet it was
+	Enemy   *Enemy
+	Bullet  *Bullet
+	X, Y    float64
+	Blocked bool
+}
+
+type EnemyDestroyed struct {
+	Player *Player
+	Enemy  *Enemy
+	ByBomb bool
+}
+
+// PowerupCollected is sent once the powerup has taken effect.
+type PowerupCollected struct {
+	Player  *Player
+	Powerup *Powerup
+}
+
+// EffectStarted is a timed effect starting or being topped up. Player is
+// who picked it up, even for the ones on the enemies.
+type EffectStarted struct {
+	Player *Player
+	Kind   effectKind
+	From   *Powerup // nil if it didn't come from a powerup
+}
+
+// BombDetonated is sent as the bomb goes off, BombFinished once its
+// shockwave has gone off the screen.
+type BombDetonated struct {
+	Player *Player
+}
+
+type BombFinished struct {
+	Player    *Player
+	Destroyed int // how many enemies it took out
+}
+
+type AnomalyStarted struct {
+	Anomaly *Anomaly
+}
+
+// AnomalyStruck is sent for each ship still flying when the anomaly hits.
+// Survived can be true outside the safe zone when a shield or spare life took it.
+type AnomalyStruck struct {
+	Player   *Player
+	Survived bool
+	Distance float64 // from the centre of the nearest safe zone as a fraction of its radius, or the inverse for a gravity well's core
+}
+
+type deathCause int
+
+const (
+	diedToEnemy deathCause = iota
+	diedToAnomaly
+	diedToBullet // friendly fire or the other ship in versus
+	diedToZone   // outside the versus zone for too long
+)
+
+// PlayerDamaged is a ship being hurt but not destroyed, PlayerDied is for that.
+type PlayerDamaged struct {
+	Player *Player
+	Damage Damage
+	Result damageResult // damageAbsorbed or damageLifeLost
+}
+
+type PlayerDied struct {
+	Player *Player
+	Cause  deathCause
+	Bullet *Bullet // the bullet that did it, for diedToBullet
+}
+
+// ScoreChanged is points being given out, X and Y are where they were earned.
+type ScoreChanged struct {
+	Player     *Player
+	Points     int
+	X, Y       float64
+	Multiplier int    // p's multiplier at the time, already counted in Points
+	Bonus      string // why there was extra, e.g. "NEAR MISS"
+	ByBomb     bool
+}
+
+// addScore is the only way points are given out, ev says how many and where.
+func (g *Game) addScore(p *Player, ev ScoreChanged) {
+	p.Score += ev.Points
+	ev.Player = p
+	ev.Multiplier = p.multiplier()
+	g.events.scoreChanged.publish(ev)
+	g.checkAnomalyMilestone()
+}
+
+// subscribeAll hooks up everything that reacts to the game. It's done once
+// when the game starts, a verifying replay skips it.
+func (g *Game) subscribeAll() {
+	subscribeAudio(&g.events)
+	g.subscribeEffects()
+	g.subscribeStats()
+	g.subscribePopups()
+	g.achievements.subscribe(g)
+}
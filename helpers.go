@@ -2,7 +2,7 @@ package main
 
 import (
 	"math"
-	"math/rand"
+	"math/rand/v2"
 )
 
 func RotatePoint(x, y, cx, cy, angle float64) (float64, float64) {
@@ -11,21 +11,21 @@ func RotatePoint(x, y, cx, cy, angle float64) (float64, float64) {
 	return cx + dx*cos - dy*sin, cy + dx*sin + dy*cos
 }
 
-func randomEdgeLocation(screenWidth, screenHeight int) (int, int, int, int) {
-	edge := rand.Intn(4) // 0=top, 1=bottom, 2=left, 3=right
+func randomEdgeLocation(rng *rand.Rand, screenWidth, screenHeight int) (int, int, int, int) {
+	edge := rng.IntN(4) // 0=top, 1=bottom, 2=left, 3=right
 	switch edge {
 	case 0: // Top
-		x := rand.Intn(screenWidth)
-		return x, -1, rand.Intn(screenWidth), screenHeight
+		x := rng.IntN(screenWidth)
+		return x, -1, rng.IntN(screenWidth), screenHeight
 	case 1: // Bottom
-		x := rand.Intn(screenWidth)
-		return x, screenHeight, rand.Intn(screenWidth), -1
+		x := rng.IntN(screenWidth)
+		return x, screenHeight, rng.IntN(screenWidth), -1
 	case 2: // Left
-		y := rand.Intn(screenHeight)
-		return -1, y, screenWidth, rand.Intn(screenHeight)
+		y := rng.IntN(screenHeight)
+		return -1, y, screenWidth, rng.IntN(screenHeight)
 	case 3: // Right
-		y := rand.Intn(screenHeight)
-		return screenWidth, y, -1, rand.Intn(screenHeight)
+		y := rng.IntN(screenHeight)
+		return screenWidth, y, -1, rng.IntN(screenHeight)
 	}
 	return 0, 0, 0, 0 // fallback, shouldn't happen
 }
@@ -36,10 +36,12 @@ func collisionDetectionBulletsAndEnemies(g *Game) {
 		if !b.Active {
 			continue
 		}
+		owner := g.players[b.Owner]
 
 		for _, e := range g.enemies {
-			// if the enemy isn't active, skip it.
-			if !e.Active {
+			// if the enemy isn't active, or has already been shot and is flashing out, skip it.
+			// a second bullet would score and split it again
+			if !e.Active || e.HitTimer > 0 {
 				continue
 			}
 
@@ -53,28 +55,26 @@ func collisionDetectionBulletsAndEnemies(g *Game) {
 			if distSq < radius*radius {
 
 				// if a bullet hits an enemy and the enemy is invincible, remove the bullet
-				if e.IsInvincible || g.invincibleEnemiesTimer > 0 {
+				if e.IsInvincible || g.enemiesInvincible() {
+					g.events.enemyHit.publish(EnemyHit{Player: owner, Enemy: e, Bullet: b, X: b.X, Y: b.Y, Blocked: true})
 					b.Active = false
 					activeBullets := g.bullets[:0]
 					for _, b := range g.bullets {
 						if b.Active {
 							activeBullets = append(activeBullets, b)
+						} else {
+							g.stats.forgetBullet(b)
 						}
 					}
 					g.bullets = activeBullets
 					continue
 				}
 
-				// make the bullet inactive, so it can't hit more than one enemy
-				if g.invincibleBulletsTimer == 0 {
+				// make the bullet inactive, so it can't hit more than one enemy,
+				// unless it's invincible and carries on through
+				g.scoreKill(owner, e, true, false)
+				if !owner.Effects.has(effectInvincibleBullets) {
 					b.Active = false
-					g.score += getScore(int(e.Radius))
-
-					// Trigger anomaly at every new 1000-point milestone
-					if g.score/1000 > g.Anomaly.lastAnomalyScore/1000 {
-						g.Anomaly.Activate()
-						g.Anomaly.lastAnomalyScore = g.score
-					}
 				}
 
 				// break enemy into smaller enemies
@@ -84,8 +84,8 @@ func collisionDetectionBulletsAndEnemies(g *Game) {
 					// if the enemy is larger than 10 radius, split it into two smaller enemies
 					// spawn them in random directions
 					for i := 0; i < 2; i++ {
-						angle := rand.Float64() * 2 * math.Pi
-						speed := 3.0
+						angle := g.rngs[rngMisc].Float64() * 2 * math.Pi
+						speed := g.difficulty.tuning().EnemySpeed
 						vx := math.Cos(angle) * speed
 						vy := math.Sin(angle) * speed
 						newEnemy := &Enemy{
@@ -97,9 +97,9 @@ func collisionDetectionBulletsAndEnemies(g *Game) {
 							Active: true,
 						}
 
-						if g.frozenEnemiesTimer > 0 {
-							angle := rand.Float64() * 2 * math.Pi
-							offset := rand.Float64() * 4 // up to 4 pixels
+						if g.enemiesFrozen() {
+							angle := g.rngs[rngMisc].Float64() * 2 * math.Pi
+							offset := g.rngs[rngMisc].Float64() * 4 // up to 4 pixels
 							newEnemy.X += math.Cos(angle) * offset
 							newEnemy.Y += math.Sin(angle) * offset
 						}
@@ -107,6 +107,8 @@ func collisionDetectionBulletsAndEnemies(g *Game) {
 						g.enemies = append(g.enemies, newEnemy)
 					}
 				}
+				g.events.enemyHit.publish(EnemyHit{Player: owner, Enemy: e, Bullet: b, X: b.X, Y: b.Y})
+				g.events.enemyDestroyed.publish(EnemyDestroyed{Player: owner, Enemy: e})
 				e.HitTimer = 6 // flash before de-spawn
 				break
 			}
@@ -114,21 +116,11 @@ func collisionDetectionBulletsAndEnemies(g *Game) {
 	}
 }
 
-func getScore(radius int) int {
-	switch {
-	case radius == 40:
-		return 10
-	case radius == 20:
-		return 20
-	case radius == 10:
-		return 40
-	}
-	return 0 // default case, shouldn't happen
-}
-
 func handleShooting(g *Game) {
-	if g.shootCooldown > 0 {
-		g.shootCooldown--
+	for _, p := range g.players {
+		if p.ShootCooldown > 0 {
+			p.ShootCooldown--
+		}
 	}
 	// Move bullets and remove inactive/out-of-bounds ones
 	screenWidth, screenHeight := g.Layout(0, 0)
@@ -137,6 +129,7 @@ func handleShooting(g *Game) {
 		b.X += b.VX
 		b.Y += b.VY
 		if b.X < 0 || b.X > float64(screenWidth) || b.Y < 0 || b.Y > float64(screenHeight) {
+			g.stats.forgetBullet(b)
 			continue
 		}
 		activeBullets = append(activeBullets, b)
@@ -192,41 +185,45 @@ func polygonCircleCollision(poly [][2]float64, cx, cy, radius float64) bool {
 }
 
 func collisionDetectionPlayerAndEnemies(g *Game) {
-	// Calculate ship polygon points (same as in DrawShip)
-	if g.hasShield {
-		return
-	}
-	cx := float64(g.playerLocation.X)
-	cy := float64(g.playerLocation.Y)
-	shipHeight := 75.0
-	shipWidth := 30.0
-	angle := g.shipAngle
-
-	topX, topY := cx, cy-shipHeight/2
-	rightX, rightY := cx+shipWidth/2, cy
-	bottomX, bottomY := cx, cy+shipHeight/4
-	leftX, leftY := cx-shipWidth/2, cy
-
-	topX, topY = RotatePoint(topX, topY, cx, cy, angle)
-	rightX, rightY = RotatePoint(rightX, rightY, cx, cy, angle)
-	bottomX, bottomY = RotatePoint(bottomX, bottomY, cx, cy, angle)
-	leftX, leftY = RotatePoint(leftX, leftY, cx, cy, angle)
+	for _, p := range g.players {
+		if !p.Alive {
+			continue
+		}
+		shipPoly := p.shipPolygon()
 
-	shipPoly := [][2]float64{
-		{topX, topY},
-		{rightX, rightY},
-		{bottomX, bottomY},
-		{leftX, leftY},
+		for _, e := range g.enemies {
+			if !e.Active {
+				continue
+			}
+			if polygonCircleCollision(shipPoly, e.X, e.Y, e.Radius) {
+				e.NearMissed = true // it touched, so no bonus for it
+				if g.damagePlayer(p, Damage{Cause: diedToEnemy, Enemy: e}) == damageIgnored {
+					continue // the shield's up
+				}
+				if g.showSplash {
+					return // that was the last ship
+				}
+				break
+			}
+			g.checkNearMiss(p, e)
+		}
 	}
+}
 
-	for _, e := range g.enemies {
-		if !e.Active {
+// collisionDetectionBulletsAndPlayers is friendly fire, only checked when it's turned on.
+func collisionDetectionBulletsAndPlayers(g *Game) {
+	for _, b := range g.bullets {
+		if !b.Active {
 			continue
 		}
-		if polygonCircleCollision(shipPoly, e.X, e.Y, e.Radius) {
-			g.previousScore = g.score
-			g.Reset()
-			return
+		for _, p := range g.players {
+			if bulletHitsPlayer(b, p) && g.damagePlayer(p, Damage{Cause: diedToBullet, Bullet: b}) != damageIgnored {
+				b.Active = false
+				if g.showSplash {
+					return
+				}
+				break
+			}
 		}
 	}
 }
@@ -271,51 +268,25 @@ func handleEnemyBounces(g *Game) {
 }
 
 func handlePowerupCollection(g *Game) {
-	cx := float64(g.playerLocation.X)
-	cy := float64(g.playerLocation.Y)
-	playerRadius := 20.0 // or whatever fits your ship
-
-	for _, p := range g.powerups {
-		if !p.Active {
+	for _, pl := range g.players {
+		if !pl.Alive {
 			continue
 		}
-		dx := cx - p.X
-		dy := cy - p.Y
-		if dx*dx+dy*dy < (playerRadius+12)*(playerRadius+12) {
-			p.Active = false
-			if p.Type == powerupShield {
-				g.ActivateShield()
-			} else if p.Type == powerupBomb {
-				if g.bombs < 2 {
-					g.bombs++
-				}
-			} else if p.Type == powerupInvincibleBullets {
-				g.invincibleBulletsTimer = 300 // 5 seconds @ 60fps
-			} else if p.Type == powerupFreezeEnemies {
-				g.frozenEnemiesTimer = 300 // 5 seconds @ 60fps
-			} else if p.Type == powerUpMystery {
-				// Randomly choose a powerup type
-				r := rand.Float64()
-				if r < 0.25 {
-					g.ActivateShield()
-				} else if r < 0.5 {
-					if g.bombs < 2 {
-						g.bombs++
-					}
-				} else if r < 0.60 {
-					g.invincibleBulletsTimer = 300 // 5 seconds @ 60fps
-				} else if r < 0.75 {
-					g.frozenEnemiesTimer = 300 // 5 seconds @ 60fps
-				} else {
-					g.invincibleEnemiesTimer = 300
-				}
+		cx := float64(pl.Location.X)
+		cy := float64(pl.Location.Y)
+
+		for _, p := range g.powerups {
+			if !p.Active {
+				continue
 			}
+			dx := cx - p.X
+			dy := cy - p.Y
+			if dx*dx+dy*dy < pl.pickupRadius()*pl.pickupRadius() {
+				p.Active = false
+				powerupInfo(p.Type).collect(g, pl, p)
+				g.events.powerupCollected.publish(PowerupCollected{Player: pl, Powerup: p})
 
+			}
 		}
 	}
 }
-
-func (g *Game) ActivateShield() {
-	g.hasShield = true
-	g.shieldTimer = 300 // shield lasts for 300 frames (5 seconds at 60fps)
-}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"slices"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/stuartstein777/go-space-shooter/resources"
+	"golang.org/x/image/font/basicfont"
+)
+
+// shieldGraceFrames is how long a ship can't be hurt once its shield runs
+// out, so it running out while inside an enemy isn't instant death.
+const shieldGraceFrames = 30
+
+// timeSlowFactor is how fast the enemies go with time slowed.
+const timeSlowFactor = 0.4
+
+// effectKind is something timed a powerup does, to a ship or to all the enemies.
+type effectKind int
+
+const (
+	effectShield effectKind = iota
+	effectInvincibleBullets
+	effectFreezeEnemies
+	effectInvincibleEnemies
+	effectMagnet
+	effectTimeSlow
+	effectDrone
+)
+
+// stackRule is what happens when an effect that's already running is picked up again.
+type stackRule int
+
+const (
+	stackRefresh stackRule = iota // the time starts over
+	stackExtend                   // the time is added on to what's left, up to MaxFrames
+	stackCount                    // it's another stack with its own time, they run down side by side
+)
+
+type effectDef struct {
+	Name      string
+	Icon      image.Rectangle // in resources.TilesImage
+	Frames    int             // how long one lasts
+	MaxFrames int             // the most stackExtend can build up
+	Stacking  stackRule
+	Enemies   bool                     // it's on all the enemies rather than one ship
+	Debuff    bool                     // it's bad for the player
+	onExpire  func(g *Game, p *Player) // p is nil for the enemies' effects
+
+	// Protects is what it does to each kind of damage to the ship while it's
+	// running, anything missing or damageFatal gets through. See resolveDamage.
+	Protects map[deathCause]damageResult
+}
+
+var effectDefs = []effectDef{
+	effectShield: {
+		Name:      "Shield",
+		Icon:      image.Rect(0, 0, 32, 32),
+		Frames:    300, // 5 seconds at 60fps
+		MaxFrames: 600,
+		Stacking:  stackExtend,
+		onExpire: func(g *Game, p *Player) {
+			p.Invulnerable = max(p.Invulnerable, shieldGraceFrames)
+		},
+		Protects: shieldRules,
+	},
+	effectInvincibleBullets: {
+		Name:     "Invincible bullets",
+		Icon:     image.Rect(64, 0, 96, 32),
+		Frames:   300,
+		Stacking: stackRefresh,
+	},
+	effectFreezeEnemies: {
+		Name:      "Freeze",
+		Icon:      image.Rect(0, 32, 32, 64),
+		Frames:    300,
+		MaxFrames: 600,
+		Stacking:  stackExtend,
+		Enemies:   true,
+	},
+	effectInvincibleEnemies: {
+		Name:     "Invincible enemies",
+		Icon:     image.Rect(96, 0, 128, 32),
+		Frames:   300,
+		Stacking: stackRefresh,
+		Enemies:  true,
+		Debuff:   true,
+	},
+	effectMagnet: {
+		Name:     "Magnet",
+		Icon:     image.Rect(128, 0, 160, 32),
+		Frames:   600,
+		Stacking: stackRefresh,
+	},
+	effectTimeSlow: {
+		Name:      "Time slow",
+		Icon:      image.Rect(96, 32, 128, 64),
+		Frames:    360,
+		MaxFrames: 720,
+		Stacking:  stackExtend,
+		Enemies:   true,
+	},
+	effectDrone: {
+		Name:     "Drone",
+		Icon:     image.Rect(128, 32, 160, 64),
+		Frames:   900,
+		Stacking: stackCount, // every pickup is another drone
+	},
+}
+
+// ActiveEffect is one effect running down. With stackCount there can be
+// several of the same kind.
+type ActiveEffect struct {
+	Kind   effectKind `json:"kind"`
+	Frames int        `json:"frames"` // left to run
+	Total  int        `json:"total"`  // what it had when it was last topped up, for the HUD
+}
+
+type ActiveEffects []ActiveEffect
+
+func (fx ActiveEffects) has(kind effectKind) bool {
+	return fx.frames(kind) > 0
+}
+
+// frames is how long the longest running one of kind has left.
+func (fx ActiveEffects) frames(kind effectKind) int {
+	longest := 0
+	for _, e := range fx {
+		if e.Kind == kind {
+			longest = max(longest, e.Frames)
+		}
+	}
+	return longest
+}
+
+func (fx ActiveEffects) stacks(kind effectKind) int {
+	n := 0
+	for _, e := range fx {
+		if e.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// add starts kind for frames, following its stacking rule if it's already running.
+func (fx *ActiveEffects) add(kind effectKind, frames int) {
+	def := effectDefs[kind]
+	for i := range *fx {
+		e := &(*fx)[i]
+		if e.Kind != kind || def.Stacking == stackCount {
+			continue
+		}
+		if def.Stacking == stackExtend {
+			e.Frames = min(e.Frames+frames, max(def.MaxFrames, frames))
+		} else {
+			e.Frames = frames
+		}
+		e.Total = e.Frames
+		return
+	}
+	*fx = append(*fx, ActiveEffect{Kind: kind, Frames: frames, Total: frames})
+}
+
+// remove ends every stack of kind straight away, without its onExpire.
+func (fx *ActiveEffects) remove(kind effectKind) {
+	kept := (*fx)[:0]
+	for _, e := range *fx {
+		if e.Kind != kind {
+			kept = append(kept, e)
+		}
+	}
+	*fx = kept
+}
+
+// tick counts everything down a frame and returns what ran out.
+func (fx *ActiveEffects) tick() []effectKind {
+	var expired []effectKind
+	kept := (*fx)[:0]
+	for _, e := range *fx {
+		e.Frames--
+		if e.Frames > 0 {
+			kept = append(kept, e)
+		} else {
+			expired = append(expired, e.Kind)
+		}
+	}
+	*fx = kept
+	return expired
+}
+
+// startEffect gives p kind, or all the enemies if that's who it's for.
+// from is the powerup it came out of, if there was one.
+func (g *Game) startEffect(p *Player, kind effectKind, from *Powerup) {
+	def := effectDefs[kind]
+	if def.Enemies {
+		g.enemyEffects.add(kind, def.Frames)
+	} else {
+		p.Effects.add(kind, def.Frames)
+	}
+	g.events.effectStarted.publish(EffectStarted{Player: p, Kind: kind, From: from})
+}
+
+// tickEffects runs every effect down a frame and calls the hooks of any that ran out.
+func (g *Game) tickEffects() {
+	for _, p := range g.players {
+		for _, kind := range p.Effects.tick() {
+			if expire := effectDefs[kind].onExpire; expire != nil {
+				expire(g, p)
+			}
+		}
+	}
+	for _, kind := range g.enemyEffects.tick() {
+		if expire := effectDefs[kind].onExpire; expire != nil {
+			expire(g, nil)
+		}
+	}
+}
+
+func (g *Game) enemiesFrozen() bool {
+	return g.enemyEffects.has(effectFreezeEnemies)
+}
+
+// enemySpeed is how much of their normal speed the enemies are moving at.
+func (g *Game) enemySpeed() float64 {
+	if g.enemiesFrozen() {
+		return 0
+	}
+	if g.enemyEffects.has(effectTimeSlow) {
+		return timeSlowFactor
+	}
+	return 1
+}
+
+func (g *Game) enemiesInvincible() bool {
+	return g.enemyEffects.has(effectInvincibleEnemies)
+}
+
+const effectIconSize = 24
+
+// kinds is each kind that's running once, however many stacks it has.
+func (fx ActiveEffects) kinds() []effectKind {
+	var kinds []effectKind
+	for _, e := range fx {
+		if !slices.Contains(kinds, e.Kind) {
+			kinds = append(kinds, e.Kind)
+		}
+	}
+	return kinds
+}
+
+// effectsWidth is how wide drawEffects' row is, for lining it up.
+func effectsWidth(fx ActiveEffects) int {
+	return max(0, len(fx.kinds())*(effectIconSize+6)-6)
+}
+
+// drawEffects is a row of icons starting at x, with a bar under each for the time left.
+func drawEffects(screen *ebiten.Image, fx ActiveEffects, x, y int, palette Palette) {
+	for i, kind := range fx.kinds() {
+		def := effectDefs[kind]
+		ix := float64(x + i*(effectIconSize+6))
+
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(effectIconSize/float64(tileSize), effectIconSize/float64(tileSize))
+		op.GeoM.Translate(ix, float64(y))
+		screen.DrawImage(resources.TilesImage.SubImage(def.Icon).(*ebiten.Image), op)
+
+		// the bar is for the longest running stack
+		left, total := 0, 1
+		for _, e := range fx {
+			if e.Kind == kind && e.Frames > left {
+				left, total = e.Frames, max(1, e.Total)
+			}
+		}
+		colour := color.RGBA{255, 255, 255, 255}
+		if def.Debuff {
+			colour = palette.Warning
+		}
+		vector.DrawFilledRect(screen, float32(ix), float32(y+effectIconSize+2), effectIconSize*float32(left)/float32(total), 3, colour, false)
+
+		if n := fx.stacks(kind); n > 1 {
+			text.Draw(screen, "x"+strconv.Itoa(n), basicfont.Face7x13, int(ix)+effectIconSize-6, y+effectIconSize, colour)
+		}
+	}
+}
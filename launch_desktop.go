@@ -0,0 +1,17 @@
+//go:build !js
+
+package main
+
+import "flag"
+
+// canHost is false in the browser, which can't accept connections.
+const canHost = true
+
+// launchOptions reads -host, -versus and -join from the command line.
+func launchOptions() (host, join string, versus bool) {
+	flag.StringVar(&host, "host", "", "host a network game on this address, e.g. "+defaultNetAddress)
+	flag.BoolVar(&versus, "versus", false, "make the hosted game versus instead of co-op")
+	flag.StringVar(&join, "join", "", "join a network game, host:port for UDP or ws://host:port/ for a WebSocket")
+	flag.Parse()
+	return host, join, versus
+}
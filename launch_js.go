@@ -0,0 +1,18 @@
+//go:build js
+
+package main
+
+import "syscall/js"
+
+// canHost is false in the browser, which can't accept connections.
+const canHost = false
+
+// launchOptions reads ?join=host:port from the page address, there's no
+// command line in the browser.
+func launchOptions() (host, join string, versus bool) {
+	params := js.Global().Get("URLSearchParams").New(js.Global().Get("location").Get("search"))
+	if v := params.Call("get", "join"); v.Type() == js.TypeString {
+		join = v.String()
+	}
+	return "", join, false
+}
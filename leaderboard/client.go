@@ -0,0 +1,95 @@
+package leaderboard
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client is a Board on the other end of an HTTP JSON API, the one Server serves.
+type Client struct {
+	BaseURL string // e.g. https://scores.example.com
+	HTTP    *http.Client
+}
+
+func NewClient(baseURL string) *Client {
+	return &Client{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		HTTP:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// errorResponse is the body the server sends with anything but a 200.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (c *Client) Submit(ctx context.Context, s Submission) (Entry, error) {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return Entry{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/scores", bytes.NewReader(body))
+	if err != nil {
+		return Entry{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var e Entry
+	err = c.do(req, &e)
+	return e, err
+}
+
+func (c *Client) Top(ctx context.Context, board string, n int) ([]Entry, error) {
+	return c.list(ctx, "/scores/top", url.Values{"board": {board}, "n": {strconv.Itoa(n)}})
+}
+
+func (c *Client) Around(ctx context.Context, board, name string, n int) ([]Entry, error) {
+	return c.list(ctx, "/scores/around", url.Values{"board": {board}, "name": {name}, "n": {strconv.Itoa(n)}})
+}
+
+func (c *Client) list(ctx context.Context, path string, query url.Values) ([]Entry, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	err = c.do(req, &entries)
+	return entries, err
+}
+
+// do sends req and decodes the reply into out. A 4xx is the server
+// refusing and comes back as ErrRejected (or ErrNotFound), anything else
+// going wrong is worth trying again later.
+func (c *Client) do(req *http.Request, out any) error {
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e errorResponse
+		json.Unmarshal(data, &e)
+		switch {
+		case resp.StatusCode == http.StatusNotFound:
+			return ErrNotFound
+		case resp.StatusCode >= 400 && resp.StatusCode < 500:
+			return fmt.Errorf("%w: %s", ErrRejected, e.Error)
+		default:
+			return fmt.Errorf("leaderboard server: %s %s", resp.Status, e.Error)
+		}
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,53 @@
+package leaderboard
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CommandVerifier runs the game's own `verify` command, so the server
+// doesn't need the simulation built in:
+//
+//	CommandVerifier{Path: "space-shooter"}
+//
+// runs `space-shooter verify -board ... -state ... -` with the replay on stdin.
+type CommandVerifier struct {
+	Path    string
+	Timeout time.Duration // zero means a minute, replays are played back flat out
+}
+
+func (v CommandVerifier) Verify(ctx context.Context, s Submission) error {
+	timeout := v.Timeout
+	if timeout == 0 {
+		timeout = time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, v.Path, "verify",
+		"-board", s.Board,
+		"-seed1", strconv.FormatUint(s.Seed1, 10),
+		"-seed2", strconv.FormatUint(s.Seed2, 10),
+		"-score", strconv.Itoa(s.Score),
+		"-frames", strconv.Itoa(s.Frames),
+		"-state", s.StateHash,
+		"-")
+	cmd.Stdin = bytes.NewReader(s.Replay)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && ctx.Err() == nil {
+		// the replay was played and didn't hold up, stderr says why
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return errors.New(msg)
+		}
+	}
+	return err
+}
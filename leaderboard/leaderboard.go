@@ -0,0 +1,84 @@
+// Package leaderboard sends scores to an online leaderboard and reads them
+// back. Board is what the game talks to, Client is the HTTP implementation
+// of it and Server is a reference server for Client to talk to.
+package leaderboard
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrRejected means the server looked at a submission and refused it,
+	// sending it again won't help.
+	ErrRejected     = errors.New("score rejected")
+	ErrBadSignature = errors.New("bad signature")
+	ErrNotFound     = errors.New("not on the leaderboard")
+)
+
+// Submission is one finished run. The seed and the hash of the recorded
+// replay let the server check the run really happened.
+type Submission struct {
+	Board      string `json:"board"` // which table, e.g. "normal"
+	Name       string `json:"name"`
+	Score      int    `json:"score"`
+	Frames     int    `json:"frames"` // how long the run lasted
+	Seed1      uint64 `json:"seed1"`
+	Seed2      uint64 `json:"seed2"`
+	ReplayHash string `json:"replayHash"` // hex sha256 of the replay
+	StateHash  string `json:"stateHash"`  // the game's checksum of the state the run ended in
+	Signature  string `json:"signature"`
+
+	// Replay is the recorded run itself. It isn't signed, ReplayHash is, and
+	// a Server with a Verifier plays it back before accepting the score.
+	Replay []byte `json:"replay,omitempty"`
+}
+
+type Entry struct {
+	Rank        int       `json:"rank"` // from 1
+	Board       string    `json:"board"`
+	Name        string    `json:"name"`
+	Score       int       `json:"score"`
+	Frames      int       `json:"frames"`
+	ReplayHash  string    `json:"replayHash"`
+	SubmittedAt time.Time `json:"submittedAt"`
+}
+
+type Board interface {
+	Submit(ctx context.Context, s Submission) (Entry, error)
+	// Top is the best n entries, best first.
+	Top(ctx context.Context, board string, n int) ([]Entry, error)
+	// Around is up to n entries with name's in the middle, or ErrNotFound.
+	Around(ctx context.Context, board, name string, n int) ([]Entry, error)
+}
+
+func (s *Submission) payload() []byte {
+	return fmt.Appendf(nil, "%s\n%s\n%d\n%d\n%d\n%d\n%s\n%s", s.Board, s.Name, s.Score, s.Frames, s.Seed1, s.Seed2, s.ReplayHash, s.StateHash)
+}
+
+// Sign sets Signature to an HMAC of everything else. The key ships with the
+// game so this only stops casual editing on the way, checking the replay is
+// what actually proves a score.
+func (s *Submission) Sign(key []byte) {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(s.payload())
+	s.Signature = hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Submission) Verify(key []byte) error {
+	sig, err := hex.DecodeString(s.Signature)
+	if err != nil {
+		return ErrBadSignature
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(s.payload())
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return ErrBadSignature
+	}
+	return nil
+}
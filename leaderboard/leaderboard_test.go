@@ -0,0 +1,210 @@
+package leaderboard
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testKey = []byte("test key")
+
+func newTestServer(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+	store, err := OpenFileStore(filepath.Join(t.TempDir(), "scores.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(store, testKey)
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+	return server, ts
+}
+
+func submission(board, name string, score, frames int) Submission {
+	s := Submission{
+		Board:      board,
+		Name:       name,
+		Score:      score,
+		Frames:     frames,
+		Seed1:      1,
+		Seed2:      2,
+		ReplayHash: strings.Repeat("ab", 32),
+		StateHash:  "0123456789abcdef",
+	}
+	s.Sign(testKey)
+	return s
+}
+
+func TestSignedSubmissionIsAccepted(t *testing.T) {
+	_, ts := newTestServer(t)
+	client := NewClient(ts.URL)
+
+	e, err := client.Submit(context.Background(), submission("normal", "ann", 500, 600))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Rank != 1 || e.Name != "ann" || e.Score != 500 {
+		t.Fatalf("got %+v", e)
+	}
+}
+
+func TestBadSignaturesAreRejected(t *testing.T) {
+	_, ts := newTestServer(t)
+	client := NewClient(ts.URL)
+
+	tampered := submission("normal", "ann", 500, 600)
+	tampered.Score = 50000
+
+	wrongKey := submission("normal", "bob", 500, 600)
+	wrongKey.Sign([]byte("some other key"))
+
+	garbage := submission("normal", "cat", 500, 600)
+	garbage.Signature = "not hex"
+
+	for name, s := range map[string]Submission{"tampered": tampered, "wrong key": wrongKey, "garbage": garbage} {
+		if _, err := client.Submit(context.Background(), s); !errors.Is(err, ErrRejected) {
+			t.Errorf("%s: got %v, want ErrRejected", name, err)
+		}
+	}
+	if top, _ := client.Top(context.Background(), "normal", 10); len(top) != 0 {
+		t.Fatalf("rejected scores went on the board: %+v", top)
+	}
+}
+
+func TestSubmissionsWithoutHashesAreRejected(t *testing.T) {
+	_, ts := newTestServer(t)
+	client := NewClient(ts.URL)
+
+	noReplay := submission("normal", "ann", 500, 600)
+	noReplay.ReplayHash = ""
+	noReplay.Sign(testKey)
+
+	// without it a verifier can't tell where the run ended up
+	noState := submission("normal", "bob", 500, 600)
+	noState.StateHash = ""
+	noState.Sign(testKey)
+
+	for name, s := range map[string]Submission{"no replay hash": noReplay, "no state hash": noState} {
+		if _, err := client.Submit(context.Background(), s); !errors.Is(err, ErrRejected) {
+			t.Errorf("%s: got %v, want ErrRejected", name, err)
+		}
+	}
+}
+
+func TestBoardsAreOrderedSeparately(t *testing.T) {
+	_, ts := newTestServer(t)
+	client := NewClient(ts.URL)
+	ctx := context.Background()
+
+	for _, s := range []Submission{
+		submission("normal", "ann", 300, 900),
+		submission("normal", "bob", 700, 900),
+		submission("normal", "cat", 300, 600), // same as ann but quicker
+		submission("hard", "dan", 100, 900),
+		submission("hard", "ann", 900, 900),
+		submission("normal", "bob", 200, 900), // worse than bob's best, kept out
+	} {
+		if _, err := client.Submit(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check := func(board string, want ...string) {
+		t.Helper()
+		top, err := client.Top(ctx, board, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for i, e := range top {
+			if e.Rank != i+1 || e.Board != board {
+				t.Errorf("%s: entry %d is %+v", board, i, e)
+			}
+			got = append(got, e.Name)
+		}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("%s: got %v, want %v", board, got, want)
+		}
+	}
+	check("normal", "bob", "cat", "ann")
+	check("hard", "ann", "dan")
+	check("easy")
+
+	around, err := client.Around(ctx, "normal", "cat", 1)
+	if err != nil || len(around) != 1 || around[0].Name != "cat" {
+		t.Fatalf("around cat: %+v %v", around, err)
+	}
+	if _, err := client.Around(ctx, "hard", "bob", 5); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("around someone not there: %v", err)
+	}
+}
+
+func TestQueueRetriesWhenOffline(t *testing.T) {
+	server, _ := newTestServer(t)
+	var offline atomic.Bool
+	offline.Store(true)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if offline.Load() {
+			writeError(w, http.StatusServiceUnavailable, "down for maintenance")
+			return
+		}
+		server.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	now := time.Unix(0, 0)
+	var saved []Submission
+	var submitted []Entry
+	q := NewQueue(NewClient(ts.URL), nil)
+	q.Now = func() time.Time { return now }
+	q.OnChange = func(pending []Submission) { saved = pending }
+	q.OnSubmitted = func(e Entry) { submitted = append(submitted, e) }
+	ctx := context.Background()
+
+	q.Add(submission("normal", "ann", 100, 600))
+	q.Add(submission("normal", "bob", 200, 600))
+	if err := q.Flush(ctx); err == nil || errors.Is(err, ErrRejected) {
+		t.Fatalf("flushing while offline: %v", err)
+	}
+	if len(q.Pending()) != 2 || len(saved) != 2 {
+		t.Fatalf("lost scores while offline: %d pending, %d saved", len(q.Pending()), len(saved))
+	}
+
+	// back online, but it waits out the backoff before trying again
+	offline.Store(false)
+	if err := q.Flush(ctx); err != nil || len(q.Pending()) != 2 {
+		t.Fatalf("retried before the backoff: %v, %d pending", err, len(q.Pending()))
+	}
+	now = now.Add(minBackoff)
+	if err := q.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Pending()) != 0 || len(saved) != 0 || len(submitted) != 2 {
+		t.Fatalf("after the retry: %d pending, %d saved, %d submitted", len(q.Pending()), len(saved), len(submitted))
+	}
+	if submitted[0].Name != "ann" || submitted[1].Name != "bob" {
+		t.Fatalf("sent out of order: %+v", submitted)
+	}
+}
+
+func TestQueueDropsRejectedScores(t *testing.T) {
+	_, ts := newTestServer(t)
+	q := NewQueue(NewClient(ts.URL), nil)
+
+	bad := submission("normal", "ann", 100, 600)
+	bad.Score = 999
+	q.Add(bad)
+	q.Add(submission("normal", "bob", 200, 600))
+	if err := q.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Pending()) != 0 {
+		t.Fatalf("a rejected score is stuck in the queue: %+v", q.Pending())
+	}
+}
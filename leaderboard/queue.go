@@ -0,0 +1,145 @@
+package leaderboard
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	minBackoff = 5 * time.Second
+	maxBackoff = 5 * time.Minute
+)
+
+// Queue holds on to submissions until the Board takes them, so scores set
+// offline go up the next time there's a connection. Rejected ones are
+// dropped, anything else is retried with a growing delay.
+type Queue struct {
+	board Board
+
+	// OnChange is called with what's still waiting whenever that changes,
+	// for saving it somewhere that survives a restart.
+	OnChange func(pending []Submission)
+	// OnSubmitted is called with each entry the board accepts.
+	OnSubmitted func(e Entry)
+	Now         func() time.Time
+
+	flushing sync.Mutex // one Flush at a time or the same score could go twice
+
+	mu      sync.Mutex
+	pending []Submission
+	backoff time.Duration
+	retryAt time.Time
+	wake    chan struct{}
+}
+
+// NewQueue starts with pending, which is whatever OnChange last saved.
+func NewQueue(board Board, pending []Submission) *Queue {
+	return &Queue{
+		board:   board,
+		pending: append([]Submission(nil), pending...),
+		Now:     time.Now,
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Add queues s and wakes up Run to send it.
+func (q *Queue) Add(s Submission) {
+	q.mu.Lock()
+	q.pending = append(q.pending, s)
+	q.retryAt = time.Time{} // a new score is worth trying straight away
+	pending := q.snapshot()
+	q.mu.Unlock()
+
+	q.changed(pending)
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) Pending() []Submission {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.snapshot()
+}
+
+func (q *Queue) snapshot() []Submission {
+	return append([]Submission(nil), q.pending...)
+}
+
+func (q *Queue) changed(pending []Submission) {
+	if q.OnChange != nil {
+		q.OnChange(pending)
+	}
+}
+
+// Flush sends everything waiting, oldest first, unless it's still backing
+// off from the last failure. It stops at the first failure that isn't a
+// rejection, the rest will go in the same order next time.
+func (q *Queue) Flush(ctx context.Context) error {
+	q.flushing.Lock()
+	defer q.flushing.Unlock()
+
+	q.mu.Lock()
+	if q.Now().Before(q.retryAt) {
+		q.mu.Unlock()
+		return nil
+	}
+	q.mu.Unlock()
+
+	for {
+		q.mu.Lock()
+		if len(q.pending) == 0 {
+			q.backoff = 0
+			q.mu.Unlock()
+			return nil
+		}
+		s := q.pending[0]
+		q.mu.Unlock()
+
+		e, err := q.board.Submit(ctx, s)
+		if err != nil && !errors.Is(err, ErrRejected) {
+			q.mu.Lock()
+			q.backoff = min(maxBackoff, max(minBackoff, q.backoff*2))
+			q.retryAt = q.Now().Add(q.backoff)
+			q.mu.Unlock()
+			return err
+		}
+
+		q.mu.Lock()
+		q.pending = q.pending[1:]
+		pending := q.snapshot()
+		q.mu.Unlock()
+
+		q.changed(pending)
+		if err == nil && q.OnSubmitted != nil {
+			q.OnSubmitted(e)
+		}
+	}
+}
+
+// Run flushes whenever something is added and retries failures until ctx is done.
+func (q *Queue) Run(ctx context.Context) {
+	for {
+		q.Flush(ctx)
+
+		q.mu.Lock()
+		wait := maxBackoff
+		if len(q.pending) > 0 {
+			wait = max(0, q.retryAt.Sub(q.Now()))
+		}
+		q.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-q.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
@@ -0,0 +1,183 @@
+package leaderboard
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPage = 10
+	maxPage     = 100
+	maxNameLen  = 24
+
+	// an hour's replay is about 300KB once it's base64'd into the JSON
+	maxSubmission = 8 << 20
+)
+
+// Server is the HTTP side of Client:
+//
+//	POST /scores                          a Submission, replies with its Entry
+//	GET  /scores/top?board=&n=            the top n
+//	GET  /scores/around?board=&name=&n=   n entries around name
+type Server struct {
+	Store Store
+	Key   []byte // the key submissions are signed with
+	Now   func() time.Time
+
+	// Verifier, if set, plays every replay back before its score goes on
+	// the board. Without one a submission only has to be signed.
+	Verifier Verifier
+}
+
+func NewServer(store Store, key []byte) *Server {
+	return &Server{Store: store, Key: key, Now: time.Now}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// the browser build is served from somewhere else, so it needs CORS to get in
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	switch {
+	case r.Method == http.MethodOptions:
+		w.WriteHeader(http.StatusNoContent)
+	case r.URL.Path == "/scores" && r.Method == http.MethodPost:
+		s.submit(w, r)
+	case r.URL.Path == "/scores/top" && r.Method == http.MethodGet:
+		s.top(w, r)
+	case r.URL.Path == "/scores/around" && r.Method == http.MethodGet:
+		s.around(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "no such endpoint")
+	}
+}
+
+func (s *Server) submit(w http.ResponseWriter, r *http.Request) {
+	var sub Submission
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSubmission)).Decode(&sub); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed submission")
+		return
+	}
+	if err := s.check(&sub); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if s.Verifier != nil {
+		if err := checkReplayHash(sub); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := s.Verifier.Verify(r.Context(), sub); err != nil {
+			log.Printf("replay from %q didn't verify: %v", sub.Name, err)
+			writeError(w, http.StatusBadRequest, "replay doesn't match the score: "+err.Error())
+			return
+		}
+	}
+
+	err := s.Store.Put(Entry{
+		Board:       sub.Board,
+		Name:        sub.Name,
+		Score:       sub.Score,
+		Frames:      sub.Frames,
+		ReplayHash:  sub.ReplayHash,
+		SubmittedAt: s.Now().UTC(),
+	})
+	if err != nil {
+		log.Printf("storing score: %v", err)
+		writeError(w, http.StatusInternalServerError, "couldn't store the score")
+		return
+	}
+
+	// reply with where they stand, which is their best and not necessarily this one
+	entries, err := s.Store.Entries(sub.Board)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "couldn't read the leaderboard")
+		return
+	}
+	for _, e := range entries {
+		if e.Name == sub.Name {
+			writeJSON(w, e)
+			return
+		}
+	}
+	writeError(w, http.StatusInternalServerError, "score went missing")
+}
+
+// check is everything about a submission that can be checked without
+// playing it back.
+func (s *Server) check(sub *Submission) error {
+	if err := sub.Verify(s.Key); err != nil {
+		return err
+	}
+	sub.Name = strings.TrimSpace(sub.Name)
+	switch {
+	case sub.Board == "":
+		return errors.New("no board")
+	case sub.Name == "" || len(sub.Name) > maxNameLen:
+		return errors.New("names must be 1 to 24 characters")
+	case sub.Score < 0 || sub.Frames <= 0:
+		return errors.New("impossible score")
+	case len(sub.ReplayHash) != 64:
+		return errors.New("no replay hash")
+	case len(sub.StateHash) != 16:
+		return errors.New("no state hash")
+	}
+	return nil
+}
+
+func (s *Server) top(w http.ResponseWriter, r *http.Request) {
+	entries, err := s.Store.Entries(r.URL.Query().Get("board"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "couldn't read the leaderboard")
+		return
+	}
+	n := pageSize(r)
+	writeJSON(w, entries[:min(n, len(entries))])
+}
+
+func (s *Server) around(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	entries, err := s.Store.Entries(query.Get("board"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "couldn't read the leaderboard")
+		return
+	}
+
+	name := query.Get("name")
+	for i, e := range entries {
+		if e.Name != name {
+			continue
+		}
+		n := pageSize(r)
+		start := max(0, min(i-n/2, len(entries)-n))
+		writeJSON(w, entries[start:min(start+n, len(entries))])
+		return
+	}
+	writeError(w, http.StatusNotFound, "not on the leaderboard")
+}
+
+func pageSize(r *http.Request) int {
+	n, err := strconv.Atoi(r.URL.Query().Get("n"))
+	if err != nil || n <= 0 {
+		return defaultPage
+	}
+	return min(n, maxPage)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("writing response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{Error: msg})
+}
@@ -0,0 +1,104 @@
+package leaderboard
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Store is where a Server keeps its scores.
+type Store interface {
+	// Put records e unless the same name already has a better score on that board.
+	Put(e Entry) error
+	// Entries is a board's scores, best first, with their ranks filled in.
+	Entries(board string) ([]Entry, error)
+}
+
+// better is the leaderboard order: higher score, then the quicker run, then whoever got there first.
+func better(a, b Entry) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	if a.Frames != b.Frames {
+		return a.Frames < b.Frames
+	}
+	return a.SubmittedAt.Before(b.SubmittedAt)
+}
+
+// FileStore keeps everything in memory and writes the lot out as JSON on
+// every change. Fine for a hobby leaderboard, swap in a database behind
+// Store for anything busier.
+type FileStore struct {
+	path string
+
+	mu     sync.Mutex
+	boards map[string][]Entry
+}
+
+// OpenFileStore loads path, starting empty if it doesn't exist yet.
+func OpenFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path, boards: map[string][]Entry{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.boards); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) Put(e Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := s.boards[e.Board]
+	for i, old := range entries {
+		if old.Name != e.Name {
+			continue
+		}
+		if !better(e, old) {
+			return nil
+		}
+		entries = append(entries[:i], entries[i+1:]...)
+		break
+	}
+	entries = append(entries, e)
+	sort.SliceStable(entries, func(i, j int) bool { return better(entries[i], entries[j]) })
+	s.boards[e.Board] = entries
+	return s.save()
+}
+
+func (s *FileStore) Entries(board string) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := append([]Entry(nil), s.boards[board]...)
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+	return entries, nil
+}
+
+// save writes to a temp file and renames it so a crash never leaves half a file behind.
+func (s *FileStore) save() error {
+	data, err := json.MarshalIndent(s.boards, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
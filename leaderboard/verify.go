@@ -0,0 +1,36 @@
+package leaderboard
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// Verifier plays a submission's replay back and checks it ends the way the
+// submission says. An error means the score isn't accepted.
+type Verifier interface {
+	Verify(ctx context.Context, s Submission) error
+}
+
+// VerifierFunc lets a plain function be a Verifier, for tests or a check
+// that doesn't need the game. The simulation lives in the game's main
+// package, so it can't be imported and run in-process, real replays are
+// checked by running the game with CommandVerifier.
+type VerifierFunc func(ctx context.Context, s Submission) error
+
+func (f VerifierFunc) Verify(ctx context.Context, s Submission) error {
+	return f(ctx, s)
+}
+
+// checkReplayHash makes sure the replay sent is the one that was signed.
+func checkReplayHash(s Submission) error {
+	if len(s.Replay) == 0 {
+		return fmt.Errorf("no replay")
+	}
+	sum := sha256.Sum256(s.Replay)
+	if hex.EncodeToString(sum[:]) != s.ReplayHash {
+		return fmt.Errorf("replay doesn't match its hash")
+	}
+	return nil
+}
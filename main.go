@@ -7,7 +7,7 @@ import (
 	"io/ioutil"
 	"log"
 	"math"
-	"math/rand"
+	"os"
 
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/opentype"
@@ -21,41 +21,86 @@ var bigFont font.Face
 
 func (g *Game) Draw(screen *ebiten.Image) {
 
-	if resources.BackgroundImage != nil {
+	if resources.BackgroundImage != nil && !g.settings.Accessibility.palette().HideStarfield {
 		op := &ebiten.DrawImageOptions{}
 		op.GeoM.Translate(0, 0) // Top-left corner
 		screen.DrawImage(resources.BackgroundImage, op)
+	} else {
+		FillScreen(screen)
 	}
 
+	// everything but the background is drawn through the effects layer so it can shake
+	world := g.effects.Begin(screen)
+	g.drawWorld(world)
+	g.effects.End(screen, world)
+
+	g.drawTouchControls(screen)
+	g.drawMenus(screen)
+	g.achievements.drawToast(screen)
+
+	if g.settings.ShowFPS {
+		DrawFPS(screen)
+	}
+}
+
+func (g *Game) drawWorld(screen *ebiten.Image) {
 	if g.showSplash {
-		DrawSplashScreen(g, screen)
+		if m := g.topMenu(); m == nil || m.isTitle {
+			DrawSplashScreen(g, screen)
+		}
 		return
 	}
 
-	if g.flashTimer > 0 {
+	// the full screen white flash is only for people who haven't asked for reduced flashing,
+	// otherwise the bomb just gets the softer tint from the effects layer
+	if g.flashTimer > 0 && g.settings.Accessibility.Flashing == flashingFull {
 		screen.Fill(color.White)
-		DrawShip(g, screen, true)
+		g.drawShips(screen, true)
 		return
 	}
 
-	if g.Anomaly.Incoming > 0 && g.Anomaly.Incoming%5 != 0 {
-		msg := "ANOMALY INCOMING!"
+	showWarning := g.Anomaly.Incoming%5 != 0
+	if g.settings.Accessibility.Flashing != flashingFull {
+		showWarning = g.settings.Accessibility.blink(g.Anomaly.Incoming, 5)
+	}
+	if g.Anomaly.Incoming > 0 && showWarning {
+		msg := g.Anomaly.variant().Telegraph
 		bounds := text.BoundString(bigFont, msg)
 		x := (1280 - bounds.Dx()) / 2
 		y := 120 // Near the top
 
-		text.Draw(screen, msg, bigFont, x, y, color.RGBA{255, 80, 80, 255})
+		text.Draw(screen, msg, bigFont, x, y, g.settings.Accessibility.palette().Warning)
+	}
+
+	if g.mode == modeVersus {
+		g.drawVersusZone(screen)
+		g.drawShips(screen, false)
+		g.particles.Draw(screen)
+		DrawBullets(g, screen)
+		g.drawVersusHUD(screen)
+		return
 	}
 
-	g.Anomaly.DrawAnomaly(screen)
-	DrawShip(g, screen, false)
+	g.Anomaly.DrawAnomaly(screen, g.settings.Accessibility)
+	g.drawShips(screen, false)
+	g.drawShockwaves(screen)
 	DrawEnemies(g, screen)
+	g.particles.Draw(screen)
 	DrawBullets(g, screen)
 	DrawPowerups(g, screen)
+	g.popups.Draw(screen)
 	DrawScore(g, screen)
 
 }
 
+func (g *Game) drawShips(screen *ebiten.Image, isBlack bool) {
+	for _, p := range g.players {
+		if p.Alive {
+			DrawShip(g, p, screen, isBlack)
+		}
+	}
+}
+
 func loadResources() {
 	// Decode an image from the image file's byte slice.
 	img, _, err := image.Decode(bytes.NewReader(resources.Tiles_png))
@@ -91,130 +136,214 @@ func loadResources() {
 }
 
 func (g *Game) Reset() {
-	g.playerLocation = Point{X: 640, Y: 480}
-	g.velocity = 0
-	g.maxSpeed = 20 // adjust as desired
-	//g.score = 0
+	g.players = nil
 	g.enemies = make([]*Enemy, 0)
 	g.bullets = make([]*Bullet, 0)
-	g.shootCooldown = bulletCooldown
 	g.showSplash = true
-	g.hasShield = false
 	g.powerups = make([]*Powerup, 0)
-	g.invincibleBulletsTimer = 0
-	g.frozenEnemiesTimer = 0
-	g.shieldTimer = 0
-	g.shipAngle = 0
+	g.enemyEffects = nil
+	g.dropDrought = 0
+	g.shockwaves = nil
 	g.flashTimer = 0
-	g.bombs = 0
-	g.score = 0
 	whiteImg = ebiten.NewImage(1, 1)
 	whiteImg.Fill(color.White)
-	g.Anomaly.Deactivate()
+	g.Anomaly = Anomaly{} // lastAnomalyScore too, or the next run would wait for this one's milestones
 }
 
-func (g *Game) HandleKeyPresses() {
-
-	if ebiten.IsKeyPressed(ebiten.KeyB) && g.bombs > 0 && g.flashTimer == 0 {
-		g.bombs--
-		g.flashTimer = 20 // flash for 20 frames (~1/3 second at 60fps)
+func (g *Game) HandleKeyPresses(p *Player, in PlayerInput) {
 
-		// Kill all enemies
-		for _, e := range g.enemies {
-			e.Active = false
-			g.score += getScore(int(e.Radius))
-		}
-
-		// Immediately remove inactive enemies
-		activeEnemies := g.enemies[:0]
-		for _, e := range g.enemies {
-			if e.Active {
-				activeEnemies = append(activeEnemies, e)
-			}
-		}
-
-		g.enemies = activeEnemies
+	// only on the frame it's pressed, holding it down doesn't use them all up.
+	// pressed with none left, it still goes off if one's picked up straight after
+	if in.Bomb && !p.BombHeld {
+		p.BombBuffer = inputBufferFrames
+	}
+	p.BombHeld = in.Bomb
+	if p.BombBuffer > 0 && p.Bombs > 0 {
+		g.detonateBomb(p)
+		p.BombBuffer = 0
 	}
 
-	if ebiten.IsKeyPressed(ebiten.KeyA) {
-		g.shipAngle -= rotateSpeed
+	if in.RotateLeft {
+		p.ShipAngle -= rotateSpeed
 	}
 
-	if ebiten.IsKeyPressed(ebiten.KeyD) {
-		g.shipAngle += rotateSpeed
+	if in.RotateRight {
+		p.ShipAngle += rotateSpeed
 	}
 
 	// Acceleration/Deceleration
-	if ebiten.IsKeyPressed(ebiten.KeyW) {
-		g.velocity += accel
-		if g.velocity > g.maxSpeed {
-			g.velocity = g.maxSpeed
+	if in.Thrust {
+		g.particles.EmitExhaust(p)
+		p.Velocity += accel
+		if p.Velocity > p.MaxSpeed {
+			p.Velocity = p.MaxSpeed
 		}
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyS) {
-		g.velocity -= accel
-		if g.velocity < 0 {
-			g.velocity = 0
+	if in.Brake {
+		p.Velocity -= accel
+		if p.Velocity < 0 {
+			p.Velocity = 0
 		}
 	}
 
-	if ebiten.IsKeyPressed(ebiten.KeySpace) && g.shootCooldown == 0 {
+	// Apply friction if not accelerating
+	if !in.Thrust && !in.Brake {
+		if p.Velocity > 0 {
+			p.Velocity -= friction
+			if p.Velocity < 0 {
+				p.Velocity = 0
+			}
+		}
+	}
+
+	// a shot pressed a little before the cooldown's done still happens when it is.
+	// an EMP anomaly stops everyone shooting
+	if in.Fire {
+		p.FireBuffer = inputBufferFrames
+	}
+	if p.FireBuffer > 0 && p.ShootCooldown == 0 && !g.Anomaly.jammed() {
+		p.FireBuffer = 0
 
-		cx := float64(g.playerLocation.X)
-		cy := float64(g.playerLocation.Y)
-		shipLength := 40.0
-		tipX := cx + shipLength*math.Sin(g.shipAngle)
-		tipY := cy - shipLength*math.Cos(g.shipAngle)
+		tipX, tipY := shipTip(p)
 
 		bullet := &Bullet{
 			X:      tipX,
 			Y:      tipY,
-			VX:     bulletSpeed * math.Sin(g.shipAngle),
-			VY:     -bulletSpeed * math.Cos(g.shipAngle),
+			VX:     bulletSpeed * math.Sin(p.ShipAngle),
+			VY:     -bulletSpeed * math.Cos(p.ShipAngle),
 			Active: true,
+			Owner:  p.ID,
 		}
 		g.bullets = append(g.bullets, bullet)
-		g.shootCooldown = 10 // frames between shots
+		p.ShootCooldown = 10 // frames between shots
+		g.events.shotFired.publish(ShotFired{Player: p, Bullet: bullet})
 	}
 }
 
-func movePlayerShip(g *Game) {
+func movePlayerShip(g *Game, p *Player) {
 
 	// Move ship forward in the direction it's facing
-	g.playerLocation.X += int(g.velocity * math.Sin(g.shipAngle))
-	g.playerLocation.Y -= int(g.velocity * math.Cos(g.shipAngle))
+	p.Location.X += int(p.Velocity * math.Sin(p.ShipAngle))
+	p.Location.Y -= int(p.Velocity * math.Cos(p.ShipAngle))
 
 	// Screen wrapping
 	screenWidth, screenHeight := g.Layout(0, 0)
-	if g.playerLocation.X < 0 {
-		g.playerLocation.X = screenWidth - 1
+	if p.Location.X < 0 {
+		p.Location.X = screenWidth - 1
 	}
-	if g.playerLocation.X >= screenWidth {
-		g.playerLocation.X = 0
+	if p.Location.X >= screenWidth {
+		p.Location.X = 0
 	}
-	if g.playerLocation.Y < 0 {
-		g.playerLocation.Y = screenHeight - 1
+	if p.Location.Y < 0 {
+		p.Location.Y = screenHeight - 1
 	}
-	if g.playerLocation.Y >= screenHeight {
-		g.playerLocation.Y = 0
+	if p.Location.Y >= screenHeight {
+		p.Location.Y = 0
 	}
 }
 
 func (g *Game) Update() error {
 
-	g.Anomaly.Update()
+	if ebiten.IsWindowBeingClosed() {
+		g.autosave()
+		g.leaveNetworkGame("")
+		return ebiten.Termination
+	}
+
+	g.achievements.updateToasts()
+	g.touch.update()
+
+	if g.network != nil {
+		g.updateNetwork()
+		return nil
+	}
+
+	// losing focus mid run saves and pauses, so nothing happens while the player is away
+	if !g.showSplash && len(g.menus) == 0 && !ebiten.IsFocused() {
+		g.autosave()
+		g.openMenu(newPauseMenu())
+		return nil
+	}
+
+	// the title screen and pause menu take over the input and stop the game
+	if g.showSplash || len(g.menus) > 0 {
+		g.pendingInputs = nil
+		g.effects.Update()
+		g.updateMenus()
+		return nil
+	}
 
-	if g.invincibleEnemiesTimer > 0 {
-		g.invincibleEnemiesTimer--
+	if pausePressed() || g.touch.pausePressed() {
+		g.openMenu(newPauseMenu())
+		return nil
+	}
+
+	g.particles.Update()
+	g.popups.Update()
+	g.pollInputs()
+
+	// hit-stop and slow motion skip simulating some frames
+	if !g.effects.Update() {
+		return nil
 	}
 
-	// if they have a shield, reduce the timer on it.
-	if g.hasShield {
-		g.shieldTimer--
-		if g.shieldTimer <= 0 {
-			g.hasShield = false
+	g.step(g.takeInputs())
+	return nil
+}
+
+// readInputs polls each player's keys, and the touch controls for player
+// one. This is the only place the keyboard reaches the game during play.
+func (g *Game) readInputs() []PlayerInput {
+	inputs := make([]PlayerInput, len(g.players))
+	for i := range g.players {
+		inputs[i] = readPlayerInput(g.settings.PlayerKeys[i], g.settings.AutoFire)
+	}
+	if len(inputs) > 0 {
+		inputs[0] = g.withTouch(g.players[0], inputs[0])
+	}
+	return inputs
+}
+
+// pollInputs reads the keys and touches every frame, including the ones
+// hit-stop and slow motion don't simulate. A shot or bomb is only pressed
+// for one frame, so it's kept until the next step rather than lost with it.
+func (g *Game) pollInputs() {
+	inputs := g.readInputs()
+	if len(g.pendingInputs) == len(inputs) {
+		for i, pending := range g.pendingInputs {
+			inputs[i].Fire = inputs[i].Fire || pending.Fire
+			inputs[i].Bomb = inputs[i].Bomb || pending.Bomb
 		}
 	}
+	g.pendingInputs = inputs
+}
+
+// takeInputs hands what's been read since the last step to the next one.
+func (g *Game) takeInputs() []PlayerInput {
+	inputs := g.pendingInputs
+	g.pendingInputs = nil
+	if len(inputs) != len(g.players) {
+		inputs = g.readInputs()
+	}
+	return inputs
+}
+
+// step advances the game by one frame. inputs has one entry per player.
+func (g *Game) step(inputs []PlayerInput) {
+	if g.replay != nil {
+		g.replay.record(inputs)
+	}
+	if g.mode == modeVersus {
+		g.stepVersus(inputs)
+		return
+	}
+
+	g.Anomaly.Update(g)
+
+	g.tickEffects()
+	for _, p := range g.players {
+		p.tickTimers()
+	}
 
 	if g.Anomaly.IsActive {
 		g.Anomaly.fadeTimer--
@@ -223,80 +352,74 @@ func (g *Game) Update() error {
 		}
 	}
 
-	if g.Anomaly.IsActive && g.Anomaly.fadeTimer == 1 { // On anomaly "strike"
-		dx := float64(g.playerLocation.X) - g.Anomaly.SafeX
-		dy := float64(g.playerLocation.Y) - g.Anomaly.SafeY
-		if dx*dx+dy*dy > g.Anomaly.SafeRadius*g.Anomaly.SafeRadius {
-			//	g.flashTimer = 20 // flash for 20 frames (~1/3 second at 60fps)
-			g.previousScore = g.score
-			g.showSplash = true
-			g.score = 0
+	if g.Anomaly.IsActive && g.Anomaly.fadeTimer == 1 && g.Anomaly.lethal() { // On anomaly "strike"
+		for _, p := range g.players {
+			if !p.Alive {
+				continue
+			}
+			distance := g.Anomaly.distance(float64(p.Location.X), float64(p.Location.Y))
+			survived := distance <= 1
+			if !survived {
+				survived = g.damagePlayer(p, Damage{Cause: diedToAnomaly}) != damageFatal
+			}
+			g.events.anomalyStruck.publish(AnomalyStruck{Player: p, Survived: survived, Distance: distance})
 		}
 	}
 
-	if g.frozenEnemiesTimer > 0 {
-		g.frozenEnemiesTimer--
-	}
-
-	if g.invincibleBulletsTimer > 0 {
-		g.invincibleBulletsTimer--
-	}
-
 	if g.flashTimer > 0 {
 		g.flashTimer--
 	}
 
-	// Handle pressing enter on the splash screen to start the game
+	// the anomaly can end the run above
 	if g.showSplash {
-		if ebiten.IsKeyPressed(ebiten.KeyEnter) {
-			g.showSplash = false
-		}
-		return nil
+		return
 	}
 
-	g.HandleKeyPresses()
-
-	// Apply friction if not accelerating
-	if !ebiten.IsKeyPressed(ebiten.KeyW) && !ebiten.IsKeyPressed(ebiten.KeyS) {
-		if g.velocity > 0 {
-			g.velocity -= friction
-			if g.velocity < 0 {
-				g.velocity = 0
-			}
+	for _, p := range g.players {
+		if !p.Alive {
+			continue
 		}
+		g.HandleKeyPresses(p, inputs[p.ID])
+		movePlayerShip(g, p)
 	}
+	g.fireDrones()
+	g.updateShockwaves()
 
-	movePlayerShip(g)
 	spawnEnemies(g)
 	handleEnemyBounces(g)
 	deSpawnEnemies(g)
 	collisionDetectionBulletsAndEnemies(g)
 	handleShooting(g)
+	if g.friendlyFire {
+		collisionDetectionBulletsAndPlayers(g)
+	}
 	collisionDetectionPlayerAndEnemies(g)
+	g.updatePowerups()
 	handlePowerupCollection(g)
-	return nil
 }
 
 func spawnEnemies(g *Game) {
-	if g.frozenEnemiesTimer > 0 {
+	if g.enemiesFrozen() {
 		return
 	}
 
-	// Randomly spawn an enemy every ~60 frames (1 second at 60fps)
-	if rand.Float64() < 1.0/60.0 {
+	difficulty := g.difficulty.tuning()
+
+	// Randomly spawn an enemy every ~60 frames (1 second at 60fps), adjusted for difficulty
+	if g.rngs[rngSpawns].Float64() < difficulty.SpawnRate/60.0 {
 		screenWidth, screenHeight := g.Layout(0, 0)
-		spawnX, spawnY, targetX, targetY := randomEdgeLocation(screenWidth, screenHeight)
+		spawnX, spawnY, targetX, targetY := randomEdgeLocation(g.rngs[rngSpawns], screenWidth, screenHeight)
 		radius := 40.0
 
 		// Calculate normalized velocity vector
 		dx := float64(targetX - spawnX)
 		dy := float64(targetY - spawnY)
 		dist := math.Hypot(dx, dy)
-		speed := 3.0 // pixels per frame
+		speed := difficulty.EnemySpeed // pixels per frame
 		vx := dx / dist * speed
 		vy := dy / dist * speed
 
-		isInvincible := rand.Float64() < 0.05 // 5% chance to be invincible
+		isInvincible := g.rngs[rngSpawns].Float64() < 0.05 // 5% chance to be invincible
 
 		enemy := &Enemy{
 			X:            float64(spawnX),
@@ -315,63 +438,18 @@ func deSpawnEnemies(g *Game) {
 	screenWidth, screenHeight := g.Layout(0, 0)
 	activeEnemies := g.enemies[:0]
 	for _, e := range g.enemies {
-		// move enemies in the direction they are travelling, assuming they arent frozen
-		if g.frozenEnemiesTimer == 0 {
-			e.X += e.VX
-			e.Y += e.VY
-		}
+		// move enemies in the direction they are travelling, slower or not at all with time slowed or frozen
+		speed := g.enemySpeed()
+		e.X += e.VX * speed
+		e.Y += e.VY * speed
 
 		if e.HitTimer > 0 {
 			e.HitTimer--
 			if e.HitTimer == 0 {
 				e.Active = false // de-spawn after flash
 
-				r := rand.Float64()
-
-				if r > 0.0 && r < 0.10 { // 10% chance to drop a powerup
-					r = rand.Float64()
-
-					if r < 0.05 { // 5% chance to drop a shield
-						powerup := &Powerup{
-							X:      e.X,
-							Y:      e.Y,
-							Type:   powerupShield,
-							Active: true,
-						}
-						g.powerups = append(g.powerups, powerup)
-					} else if r < 0.1 { // 5% chance to drop a bomb
-						powerup := &Powerup{
-							X:      e.X,
-							Y:      e.Y,
-							Type:   powerupBomb,
-							Active: true,
-						}
-						g.powerups = append(g.powerups, powerup)
-					} else if r < 0.15 { // 5% chance to drop a freeze enemies
-						powerup := &Powerup{
-							X:      e.X,
-							Y:      e.Y,
-							Type:   powerupFreezeEnemies,
-							Active: true,
-						}
-						g.powerups = append(g.powerups, powerup)
-					} else if r > 0.15 && r < 0.3 { // 15% chance to drop invincible bullets
-						powerup := &Powerup{
-							X:      e.X,
-							Y:      e.Y,
-							Type:   powerupInvincibleBullets,
-							Active: true,
-						}
-						g.powerups = append(g.powerups, powerup)
-					} else { // else its a mystery powerup
-						powerup := &Powerup{
-							X:      e.X,
-							Y:      e.Y,
-							Type:   powerUpMystery,
-							Active: true,
-						}
-						g.powerups = append(g.powerups, powerup)
-					}
+				if powerupType, ok := g.rollDrop(e); ok {
+					g.dropPowerup(e.X, e.Y, powerupType)
 				}
 
 				continue
@@ -391,19 +469,48 @@ func deSpawnEnemies(g *Game) {
 }
 
 func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	// the game calls this too, with no window, to get the screen size
+	if outsideWidth > 0 && outsideHeight > 0 {
+		g.touch.windowW, g.touch.windowH = outsideWidth, outsideHeight
+	}
 	return 1280, 960
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		os.Exit(verifyCommand(os.Args[2:]))
+	}
+
 	loadResources()
 	resources.LoadBackground()
+	loadSounds()
 
 	game := &Game{}
+	game.effects.Config = defaultEffectsConfig()
+	game.settings = loadSettings()
+	game.achievements = loadAchievements()
+	game.subscribeAll()
+	game.applySettings()
+	game.setupLeaderboard()
 	game.Reset()
+
+	if host, join, versus := launchOptions(); host != "" {
+		mode := modeSurvival
+		if versus {
+			mode = modeVersus
+		}
+		game.hostGame(host, mode)
+	} else if join != "" {
+		game.joinGame(join)
+	}
 	//	game.hasShield = true
 	//	game.shieldTimer = 100000 for debugging to just be invincible.
 	ebiten.SetWindowSize(1280, 960)
 	ebiten.SetWindowTitle("Space Shooter")
+
+	// we want to see the window closing and focus being lost so the run can be saved
+	ebiten.SetWindowClosingHandled(true)
+	ebiten.SetRunnableOnUnfocused(true)
 	if err := ebiten.RunGame(game); err != nil {
 		log.Fatal(err)
 	}
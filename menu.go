@@ -0,0 +1,498 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"math/rand/v2"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"golang.org/x/image/font/basicfont"
+)
+
+type menuItem struct {
+	label  func(g *Game) string
+	action func(g *Game)          // enter, space or the A button
+	adjust func(g *Game, dir int) // left and right, dir is -1 or 1
+}
+
+type Menu struct {
+	title    string
+	items    []menuItem
+	selected int
+	onClose  func(g *Game)
+	isTitle  bool   // the title screen draws its menu under the controls text
+	binding  Action // when set the next key pressed is bound to this action
+	player   int    // whose keys a controls menu is editing
+	wait     int    // frames before it takes any input
+	itemsY   int    // where the first item was last drawn, for tapping them
+}
+
+// menuWaitFrames keeps the title screen from reacting straight away when a
+// run ends, space and enter are shoot keys as well as select.
+const menuWaitFrames = 45
+
+type menuInput struct {
+	up, down, left, right bool
+	confirm, back         bool
+}
+
+// readMenuInput merges the keyboard and any standard layout gamepads.
+// Menus are edge triggered, holding a direction doesn't scroll.
+func readMenuInput() menuInput {
+	var in menuInput
+	in.up = inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) || inpututil.IsKeyJustPressed(ebiten.KeyW)
+	in.down = inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) || inpututil.IsKeyJustPressed(ebiten.KeyS)
+	in.left = inpututil.IsKeyJustPressed(ebiten.KeyArrowLeft) || inpututil.IsKeyJustPressed(ebiten.KeyA)
+	in.right = inpututil.IsKeyJustPressed(ebiten.KeyArrowRight) || inpututil.IsKeyJustPressed(ebiten.KeyD)
+	in.confirm = inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeySpace)
+	in.back = inpututil.IsKeyJustPressed(ebiten.KeyEscape) || inpututil.IsKeyJustPressed(ebiten.KeyBackspace)
+
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if !ebiten.IsStandardGamepadLayoutAvailable(id) {
+			continue
+		}
+		pressed := func(b ebiten.StandardGamepadButton) bool {
+			return inpututil.IsStandardGamepadButtonJustPressed(id, b)
+		}
+		in.up = in.up || pressed(ebiten.StandardGamepadButtonLeftTop)
+		in.down = in.down || pressed(ebiten.StandardGamepadButtonLeftBottom)
+		in.left = in.left || pressed(ebiten.StandardGamepadButtonLeftLeft)
+		in.right = in.right || pressed(ebiten.StandardGamepadButtonLeftRight)
+		in.confirm = in.confirm || pressed(ebiten.StandardGamepadButtonRightBottom)
+		in.back = in.back || pressed(ebiten.StandardGamepadButtonRightRight)
+	}
+	return in
+}
+
+// tapped is the item a finger has just come down on. Each one is a 20
+// pixel row, the text sits on the bottom of it.
+func (m *Menu) tapped() (int, bool) {
+	// not before it's been drawn, and waiting for a key isn't something a tap can answer
+	if m.itemsY == 0 || m.binding != "" {
+		return 0, false
+	}
+	for _, id := range inpututil.AppendJustPressedTouchIDs(nil) {
+		_, y := ebiten.TouchPosition(id)
+		row := y - (m.itemsY - 15)
+		if row >= 0 && row/20 < len(m.items) {
+			return row / 20, true
+		}
+	}
+	return 0, false
+}
+
+// pausePressed is escape on the keyboard or start on a gamepad.
+func pausePressed() bool {
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		return true
+	}
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if ebiten.IsStandardGamepadLayoutAvailable(id) &&
+			inpututil.IsStandardGamepadButtonJustPressed(id, ebiten.StandardGamepadButtonCenterRight) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Game) openMenu(m *Menu) {
+	g.menus = append(g.menus, m)
+}
+
+func (g *Game) closeMenu() {
+	if len(g.menus) == 0 {
+		return
+	}
+	m := g.menus[len(g.menus)-1]
+	g.menus = g.menus[:len(g.menus)-1]
+	if m.onClose != nil {
+		m.onClose(g)
+	}
+}
+
+func (g *Game) updateMenus() {
+	if g.showSplash && len(g.menus) == 0 {
+		title := newTitleMenu(g)
+		title.wait = menuWaitFrames
+		g.openMenu(title)
+	}
+	if len(g.menus) == 0 {
+		return
+	}
+	m := g.menus[len(g.menus)-1]
+	if m.wait > 0 {
+		m.wait--
+		return
+	}
+
+	if m.binding != "" {
+		for _, key := range inpututil.AppendJustPressedKeys(nil) {
+			if key != ebiten.KeyEscape {
+				g.settings.PlayerKeys[m.player].bind(m.binding, key)
+			}
+			m.binding = ""
+			break
+		}
+		return
+	}
+
+	in := readMenuInput()
+	if i, ok := m.tapped(); ok {
+		m.selected = i
+		in.confirm = true
+	}
+	switch {
+	case in.up:
+		m.selected = (m.selected + len(m.items) - 1) % len(m.items)
+	case in.down:
+		m.selected = (m.selected + 1) % len(m.items)
+	case in.left || in.right:
+		if item := m.items[m.selected]; item.adjust != nil {
+			dir := 1
+			if in.left {
+				dir = -1
+			}
+			item.adjust(g, dir)
+			g.applySettings()
+		}
+	case in.confirm:
+		item := m.items[m.selected]
+		if item.action != nil {
+			item.action(g)
+		} else if item.adjust != nil {
+			item.adjust(g, 1)
+		}
+		g.applySettings()
+	case in.back && !m.isTitle:
+		g.closeMenu()
+	}
+}
+
+func (g *Game) drawMenu(screen *ebiten.Image, m *Menu, y int) {
+	w := screen.Bounds().Dx()
+	face := basicfont.Face7x13
+
+	if m.title != "" {
+		bounds := text.BoundString(face, m.title)
+		text.Draw(screen, m.title, face, (w-bounds.Dx())/2, y, color.White)
+		y += 40
+	}
+
+	m.itemsY = y
+	for i, item := range m.items {
+		label := item.label(g)
+		col := color.Color(color.White)
+		if i == m.selected {
+			label = "> " + label + " <"
+			col = color.RGBA{255, 200, 0, 255}
+			if m.binding != "" {
+				label = "> Press a key for " + actionNames[m.binding] + " (ESC to cancel) <"
+			}
+		}
+		bounds := text.BoundString(face, label)
+		text.Draw(screen, label, face, (w-bounds.Dx())/2, y+20*i, col)
+	}
+}
+
+// drawMenus draws whichever menu is on top. Menus other than the title
+// screen get a dark backdrop so they read clearly over the game.
+func (g *Game) drawMenus(screen *ebiten.Image) {
+	if len(g.menus) == 0 {
+		return
+	}
+	m := g.menus[len(g.menus)-1]
+	if m.isTitle {
+		return // DrawSplashScreen places the title menu itself
+	}
+
+	w, h := screen.Bounds().Dx(), screen.Bounds().Dy()
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(float64(w), float64(h))
+	op.ColorScale.Scale(0, 0, 0, 0.75)
+	screen.DrawImage(whiteImg, op)
+
+	g.drawMenu(screen, m, h/2-20*len(m.items)/2-40)
+}
+
+func (g *Game) topMenu() *Menu {
+	if len(g.menus) == 0 {
+		return nil
+	}
+	return g.menus[len(g.menus)-1]
+}
+
+func onOff(b bool) string {
+	if b {
+		return "On"
+	}
+	return "Off"
+}
+
+func toggleItem(name string, value func(g *Game) *bool) menuItem {
+	return menuItem{
+		label: func(g *Game) string { return name + ": " + onOff(*value(g)) },
+		adjust: func(g *Game, dir int) {
+			v := value(g)
+			*v = !*v
+		},
+	}
+}
+
+// choiceItem cycles through a fixed list of options with left and right.
+func choiceItem(name string, options []string, get func(g *Game) int, set func(g *Game, v int)) menuItem {
+	return menuItem{
+		label: func(g *Game) string {
+			v := get(g)
+			if v < 0 || v >= len(options) {
+				return name + ": ?"
+			}
+			return name + ": " + options[v]
+		},
+		adjust: func(g *Game, dir int) {
+			set(g, (get(g)+dir+len(options))%len(options))
+		},
+	}
+}
+
+func buttonItem(name string, action func(g *Game)) menuItem {
+	return menuItem{
+		label:  func(g *Game) string { return name },
+		action: action,
+	}
+}
+
+func backItem() menuItem {
+	return buttonItem("Back", func(g *Game) { g.closeMenu() })
+}
+
+// RunConfig is everything that's decided before a run starts. Network games
+// send the host's to the client so both machines start identically.
+type RunConfig struct {
+	Seed1          uint64     `json:"seed1"`
+	Seed2          uint64     `json:"seed2"`
+	Mode           GameMode   `json:"mode"`
+	Players        int        `json:"players"`
+	Difficulty     Difficulty `json:"difficulty"`
+	FriendlyFire   bool       `json:"friendlyFire"`
+	BombInvincible bombRule   `json:"bombInvincible,omitempty"`
+	Daily          string     `json:"daily,omitempty"`    // the date, for a daily challenge
+	Practice       bool       `json:"practice,omitempty"` // a daily challenge that's already been played today
+}
+
+// newRunConfig rolls a new seed and takes the rest from the settings.
+func (g *Game) newRunConfig(mode GameMode, players int) RunConfig {
+	return RunConfig{
+		Seed1:          rand.Uint64(),
+		Seed2:          rand.Uint64(),
+		Mode:           mode,
+		Players:        players,
+		Difficulty:     g.settings.Difficulty,
+		FriendlyFire:   g.settings.FriendlyFire,
+		BombInvincible: g.settings.BombInvincible,
+	}
+}
+
+// startGame begins a fresh local run.
+func (g *Game) startGame(mode GameMode, players int) {
+	g.newRun(g.newRunConfig(mode, players))
+}
+
+func (g *Game) newRun(cfg RunConfig) {
+	g.Reset()
+	g.particles.Clear()
+	g.popups.Clear()
+	g.effects.Reset()
+	g.seedRun(cfg.Seed1, cfg.Seed2)
+	g.mode = cfg.Mode
+	g.difficulty = cfg.Difficulty
+	g.friendlyFire = cfg.FriendlyFire
+	g.bombInvincible = cfg.BombInvincible
+	g.daily = cfg.Daily
+	g.practice = cfg.Practice
+	g.replay = newReplay(cfg)
+	g.achievements.newRun(false)
+	g.stats = newRunStats(cfg.Players)
+	g.finalState = ""
+	for i := 0; i < cfg.Players; i++ {
+		g.players = append(g.players, newPlayer(i, cfg.Players, cfg.Difficulty.tuning().Lives))
+	}
+	if g.mode == modeVersus {
+		g.versus = newVersus()
+		for _, p := range g.players {
+			g.placeVersusShip(p)
+		}
+	}
+	g.lastMatch = nil
+	g.menus = nil
+	g.showSplash = false
+	g.netStatus = ""
+}
+
+func newTitleMenu(g *Game) *Menu {
+	start := "Start"
+	coop := "Co-op"
+	versus := "Versus"
+	if g.lastMatch != nil {
+		versus = "Rematch"
+	} else if len(g.previousScores) == 1 {
+		start = "Play again"
+	} else if len(g.previousScores) > 1 {
+		coop = "Co-op again"
+	}
+	m := &Menu{isTitle: true}
+	if g.network != nil {
+		m.items = append(m.items, buttonItem("Cancel", func(g *Game) { g.leaveNetworkGame("") }))
+		return m
+	}
+	if hasAutosave() {
+		m.items = append(m.items, buttonItem("Continue", func(g *Game) {
+			if err := g.continueGame(); err != nil {
+				log.Printf("continuing game: %v", err)
+				clearAutosave()
+				g.menus = nil // rebuilds the title menu without Continue
+			}
+		}))
+	}
+	m.items = append(m.items,
+		buttonItem(start, func(g *Game) { g.startGame(modeSurvival, 1) }),
+		buttonItem(dailyLabel(), func(g *Game) { g.startDaily() }),
+		buttonItem(coop, func(g *Game) { g.startGame(modeSurvival, 2) }),
+		buttonItem(versus, func(g *Game) { g.startGame(modeVersus, 2) }),
+	)
+	if canHost {
+		m.items = append(m.items,
+			buttonItem("Host network co-op", func(g *Game) { g.hostGame(defaultNetAddress, modeSurvival) }),
+			buttonItem("Host network versus", func(g *Game) { g.hostGame(defaultNetAddress, modeVersus) }),
+		)
+	}
+	if g.online != nil {
+		m.items = append(m.items,
+			buttonItem("Leaderboard", func(g *Game) { g.openMenu(newLeaderboardMenu(g, boardName(g.settings.Difficulty))) }),
+			buttonItem("Daily leaderboard", func(g *Game) { g.openMenu(newLeaderboardMenu(g, dailyBoard(today()))) }),
+		)
+	}
+	m.items = append(m.items,
+		buttonItem("Achievements", func(g *Game) { g.openMenu(newAchievementsMenu(g)) }),
+		buttonItem("Settings", func(g *Game) { g.openMenu(newSettingsMenu()) }),
+	)
+	return m
+}
+
+func newPauseMenu() *Menu {
+	return &Menu{
+		title: "PAUSED",
+		items: []menuItem{
+			buttonItem("Resume", func(g *Game) { g.closeMenu() }),
+			buttonItem("Settings", func(g *Game) { g.openMenu(newSettingsMenu()) }),
+			// the run is saved so it can be picked up again with Continue
+			buttonItem("Quit to title", func(g *Game) {
+				g.autosave()
+				g.menus = nil
+				g.previousScores = nil
+				g.lastMatch = nil
+				g.Reset()
+			}),
+		},
+	}
+}
+
+func newSettingsMenu() *Menu {
+	particleDetailNames := make([]string, len(particleDetails))
+	for i, d := range particleDetails {
+		particleDetailNames[i] = d.Name
+	}
+	difficultyNames := make([]string, len(difficulties))
+	for i, d := range difficulties {
+		difficultyNames[i] = d.Name
+	}
+
+	return &Menu{
+		title: "SETTINGS",
+		items: []menuItem{
+			{
+				label: func(g *Game) string { return fmt.Sprintf("Volume: %d%%", int(g.settings.Volume*100+0.5)) },
+				adjust: func(g *Game, dir int) {
+					g.settings.Volume = min(1, max(0, g.settings.Volume+0.1*float64(dir)))
+				},
+			},
+			toggleItem("Fullscreen", func(g *Game) *bool { return &g.settings.Fullscreen }),
+			toggleItem("VSync", func(g *Game) *bool { return &g.settings.VSync }),
+			choiceItem("Difficulty", difficultyNames,
+				func(g *Game) int { return int(g.settings.Difficulty) },
+				func(g *Game, v int) { g.settings.Difficulty = Difficulty(v) }),
+			toggleItem("Show FPS", func(g *Game) *bool { return &g.settings.ShowFPS }),
+			choiceItem("Particles", particleDetailNames,
+				func(g *Game) int { return int(g.settings.Particles) },
+				func(g *Game, v int) { g.settings.Particles = ParticleDetail(v) }),
+			toggleItem("Auto-fire", func(g *Game) *bool { return &g.settings.AutoFire }),
+			toggleItem("Friendly fire", func(g *Game) *bool { return &g.settings.FriendlyFire }),
+			choiceItem("Bombs vs invincible", bombRuleNames,
+				func(g *Game) int { return int(g.settings.BombInvincible) },
+				func(g *Game, v int) { g.settings.BombInvincible = bombRule(v) }),
+			toggleItem("Save run history", func(g *Game) *bool { return &g.settings.RunHistory }),
+			buttonItem("Player 1 controls", func(g *Game) { g.openMenu(newControlsMenu(0)) }),
+			buttonItem("Player 2 controls", func(g *Game) { g.openMenu(newControlsMenu(1)) }),
+			buttonItem("Accessibility", func(g *Game) { g.openMenu(newAccessibilityMenu()) }),
+			backItem(),
+		},
+		onClose: func(g *Game) { saveSettings(g.settings) },
+	}
+}
+
+func newControlsMenu(player int) *Menu {
+	m := &Menu{title: fmt.Sprintf("PLAYER %d CONTROLS", player+1), player: player}
+	for _, a := range actions {
+		m.items = append(m.items, menuItem{
+			label:  func(g *Game) string { return actionNames[a] + ": " + g.settings.PlayerKeys[player][a].String() },
+			action: func(g *Game) { m.binding = a },
+		})
+	}
+	m.items = append(m.items,
+		buttonItem("Reset to defaults", func(g *Game) { g.settings.PlayerKeys[player] = defaultKeyBindings(player) }),
+		backItem(),
+	)
+	return m
+}
+
+func newAccessibilityMenu() *Menu {
+	paletteNames := make([]string, len(palettes))
+	for i, p := range palettes {
+		paletteNames[i] = p.Name
+	}
+
+	return &Menu{
+		title: "ACCESSIBILITY",
+		items: []menuItem{
+			choiceItem("Flashing", flashingModeNames,
+				func(g *Game) int { return int(g.settings.Accessibility.Flashing) },
+				func(g *Game, v int) { g.settings.Accessibility.Flashing = FlashingMode(v) }),
+			choiceItem("Colours", paletteNames,
+				func(g *Game) int { return int(g.settings.Accessibility.Palette) },
+				func(g *Game, v int) { g.settings.Accessibility.Palette = PaletteID(v) }),
+			toggleItem("High contrast", func(g *Game) *bool { return &g.settings.Accessibility.HighContrast }),
+			toggleItem("Shape cues", func(g *Game) *bool { return &g.settings.Accessibility.ShapeCues }),
+			toggleItem("Screen shake", func(g *Game) *bool { return &g.settings.Accessibility.ScreenShake }),
+			backItem(),
+		},
+	}
+}
+
+// controlsHelp is the controls text on the title screen, built from the current bindings.
+func controlsHelp(keys [maxPlayers]KeyBindings) string {
+	var b strings.Builder
+	k := keys[0]
+	fmt.Fprintf(&b, "%s/%s - Rotate\n", k[actionRotateLeft], k[actionRotateRight])
+	fmt.Fprintf(&b, "%s/%s - Accelerate/Decelerate\n", k[actionThrust], k[actionBrake])
+	fmt.Fprintf(&b, "%s - Shoot\n", strings.ToUpper(k[actionFire].String()))
+	fmt.Fprintf(&b, "%s - Bomb\n", k[actionBomb])
+	b.WriteString("ESC - Pause\n\n")
+
+	k = keys[1]
+	fmt.Fprintf(&b, "Player 2: %s/%s/%s/%s, %s to shoot, %s for bomb",
+		k[actionRotateLeft], k[actionThrust], k[actionRotateRight], k[actionBrake], k[actionFire], k[actionBomb])
+	return b.String()
+}
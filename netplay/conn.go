@@ -0,0 +1,117 @@
+// Package netplay runs a two player game across machines. Both peers run
+// the same deterministic simulation in lockstep, only inputs go over the
+// wire, with a short input delay to hide the latency. The host is the
+// authority: it picks the seed and settings, and if the client ever drifts
+// out of sync the host sends it a full copy of the game state.
+package netplay
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	ErrClosed   = errors.New("connection closed")
+	ErrTimeout  = errors.New("the other player stopped responding")
+	ErrPeerLeft = errors.New("the other player left")
+)
+
+// maxMessage is the most a Conn has to carry in one message, the largest
+// UDP payload. The session refuses to send anything bigger rather than have
+// it quietly lost.
+const maxMessage = 65507
+
+// Conn is an unreliable message pipe to one peer, like a UDP socket.
+// Messages may be dropped or arrive out of order, the session copes with both.
+type Conn interface {
+	Send(msg []byte) error
+	// Receive never blocks, ok is false when nothing is waiting.
+	Receive() (msg []byte, ok bool)
+	Close() error
+}
+
+// Listener waits for a peer to join a hosted game.
+type Listener interface {
+	// Accept never blocks, ok is false until someone has connected.
+	Accept() (conn Conn, ok bool)
+	Addr() string
+	Close() error
+}
+
+// queueSize is how many messages can be waiting before new ones are dropped.
+const queueSize = 1024
+
+// inbox is the receive side shared by the transports, their read loops push
+// into it and Receive pulls out of it.
+type inbox struct {
+	messages  chan []byte
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newInbox() *inbox {
+	return &inbox{
+		messages: make(chan []byte, queueSize),
+		closed:   make(chan struct{}),
+	}
+}
+
+// push drops the message if the queue is full, the same as a full socket buffer would.
+func (in *inbox) push(msg []byte) {
+	select {
+	case in.messages <- msg:
+	default:
+	}
+}
+
+func (in *inbox) Receive() ([]byte, bool) {
+	select {
+	case msg := <-in.messages:
+		return msg, true
+	default:
+		return nil, false
+	}
+}
+
+func (in *inbox) close() {
+	in.closeOnce.Do(func() { close(in.closed) })
+}
+
+func (in *inbox) isClosed() bool {
+	select {
+	case <-in.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// pipeConn is one end of an in-process Pipe.
+type pipeConn struct {
+	*inbox
+	peer *pipeConn
+}
+
+// Pipe returns two connected in-process Conns, for running both peers in
+// one program. Wrap them in NewLossy to add latency and packet loss.
+func Pipe() (Conn, Conn) {
+	a := &pipeConn{inbox: newInbox()}
+	b := &pipeConn{inbox: newInbox()}
+	a.peer, b.peer = b, a
+	return a, b
+}
+
+func (c *pipeConn) Send(msg []byte) error {
+	if c.isClosed() {
+		return ErrClosed
+	}
+	if !c.peer.isClosed() {
+		c.peer.push(append([]byte(nil), msg...))
+	}
+	return nil
+}
+
+func (c *pipeConn) Close() error {
+	c.close()
+	return nil
+}
@@ -0,0 +1,59 @@
+//go:build !js
+
+package netplay
+
+import (
+	"errors"
+	"strings"
+)
+
+// Listen hosts a game on addr. Desktop peers join over UDP and browsers over
+// a WebSocket on the same port number, whichever connects first is the
+// other player.
+func Listen(addr string) (Listener, error) {
+	udp, err := listenUDP(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	// an ephemeral ":0" has to become the port UDP actually got, or the two could differ
+	wsAddr := addr
+	if strings.HasSuffix(addr, ":0") {
+		wsAddr = udp.Addr()
+	}
+	ws, err := listenWebSocket(wsAddr)
+	if err != nil {
+		udp.Close()
+		return nil, err
+	}
+	return &multiListener{udp: udp, ws: ws}, nil
+}
+
+type multiListener struct {
+	udp *udpListener
+	ws  *wsListener
+}
+
+func (l *multiListener) Accept() (Conn, bool) {
+	if c, ok := l.udp.Accept(); ok {
+		return c, true
+	}
+	return l.ws.Accept()
+}
+
+func (l *multiListener) Addr() string {
+	return l.udp.Addr()
+}
+
+func (l *multiListener) Close() error {
+	return errors.Join(l.udp.Close(), l.ws.Close())
+}
+
+// Dial joins a hosted game. Addresses starting ws:// or wss:// use a
+// WebSocket, anything else is a UDP host:port.
+func Dial(addr string) (Conn, error) {
+	if strings.HasPrefix(addr, "ws://") || strings.HasPrefix(addr, "wss://") {
+		return DialWebSocket(addr)
+	}
+	return DialUDP(addr)
+}
@@ -0,0 +1,99 @@
+package netplay
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// LossyOptions describes how bad a network NewLossy pretends to be.
+type LossyOptions struct {
+	Latency time.Duration // added to every message
+	Jitter  time.Duration // up to this much more on top, so messages can arrive out of order
+	Loss    float64       // chance of a message being dropped, 0..1
+	Seed    uint64        // the same seed drops the same messages
+	Now     func() time.Time
+}
+
+type delayed struct {
+	at  time.Time
+	msg []byte
+}
+
+// lossyConn holds back outgoing messages until they're due and drops some of them.
+type lossyConn struct {
+	conn Conn
+	opts LossyOptions
+	rng  *rand.Rand
+
+	mu      sync.Mutex
+	pending []delayed
+}
+
+// NewLossy wraps conn so that everything sent through it is delayed and
+// sometimes lost. Messages are handed on when the wrapper is next used, so
+// it needs Send or Receive calling every frame, which a Session does anyway.
+func NewLossy(conn Conn, opts LossyOptions) Conn {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &lossyConn{
+		conn: conn,
+		opts: opts,
+		rng:  rand.New(rand.NewPCG(opts.Seed, opts.Seed^0x9e3779b97f4a7c15)),
+	}
+}
+
+func (c *lossyConn) Send(msg []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.rng.Float64() >= c.opts.Loss {
+		delay := c.opts.Latency
+		if c.opts.Jitter > 0 {
+			delay += time.Duration(c.rng.Int64N(int64(c.opts.Jitter)))
+		}
+		c.pending = append(c.pending, delayed{
+			at:  c.opts.Now().Add(delay),
+			msg: append([]byte(nil), msg...),
+		})
+	}
+	return c.flush()
+}
+
+func (c *lossyConn) Receive() ([]byte, bool) {
+	c.mu.Lock()
+	c.flush()
+	c.mu.Unlock()
+	return c.conn.Receive()
+}
+
+// flush sends on everything that's due, called with mu held.
+func (c *lossyConn) flush() error {
+	now := c.opts.Now()
+	waiting := c.pending[:0]
+	var err error
+	for _, d := range c.pending {
+		if d.at.After(now) {
+			waiting = append(waiting, d)
+			continue
+		}
+		if sendErr := c.conn.Send(d.msg); sendErr != nil {
+			err = sendErr
+		}
+	}
+	c.pending = waiting
+	return err
+}
+
+// Close hands on whatever is still in flight first, a real network would
+// still deliver what was sent before the socket closed.
+func (c *lossyConn) Close() error {
+	c.mu.Lock()
+	for _, d := range c.pending {
+		c.conn.Send(d.msg)
+	}
+	c.pending = nil
+	c.mu.Unlock()
+	return c.conn.Close()
+}
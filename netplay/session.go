@@ -0,0 +1,447 @@
+package netplay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Players is how many peers a session has, the host is player 0.
+const Players = 2
+
+// ProtocolVersion changes whenever the messages below do.
+const ProtocolVersion = 1
+
+const (
+	// history is how many frames of inputs and checksums are kept, enough
+	// to replay from a snapshot the client was sent.
+	history = 600
+	// maxResend caps how many unacknowledged inputs go in one message.
+	maxResend = 120
+	// maxCatchUp is the most frames Advance will hand back at once after a stall.
+	maxCatchUp = 8
+	// byeRepeats is how many times the bye is sent, nothing resends it if it's lost.
+	byeRepeats = 3
+)
+
+type State int
+
+const (
+	StateConnecting State = iota // waiting for the handshake
+	StateRunning
+	StateClosed
+)
+
+type Options struct {
+	// Delay is how many frames after being pressed an input takes effect.
+	// It only matters on the host, the client is told the host's.
+	Delay int
+	// Timeout is how long without hearing anything before giving up.
+	Timeout time.Duration
+	// GameVersion must be the same on both peers, lockstep only works if
+	// they're running exactly the same simulation.
+	GameVersion int
+	Now         func() time.Time
+}
+
+func (o *Options) fill() {
+	if o.Delay <= 0 {
+		o.Delay = 3
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 5 * time.Second
+	}
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+}
+
+const (
+	msgHello    = "hello"
+	msgWelcome  = "welcome"
+	msgReject   = "reject"
+	msgInput    = "input"
+	msgChecksum = "checksum"
+	msgSnapshot = "snapshot"
+	msgBye      = "bye"
+)
+
+type message struct {
+	Type        string          `json:"type"`
+	Protocol    int             `json:"protocol,omitempty"`
+	GameVersion int             `json:"gameVersion,omitempty"`
+	Delay       int             `json:"delay,omitempty"`
+	Setup       json.RawMessage `json:"setup,omitempty"`
+	Reason      string          `json:"reason,omitempty"`
+	Start       int             `json:"start,omitempty"` // frame of the first entry in Inputs
+	Inputs      []byte          `json:"inputs,omitempty"`
+	Ack         int             `json:"ack,omitempty"` // every input before this frame has arrived
+	Frame       int             `json:"frame,omitempty"`
+	Checksum    uint64          `json:"checksum,omitempty"`
+	State       []byte          `json:"state,omitempty"`
+}
+
+// Session keeps two peers' simulations in lockstep. Each frame the game
+// passes its local input to Advance and steps the simulation once for every
+// set of inputs it gets back. Inputs are one byte per player, what the bits
+// mean is up to the game.
+type Session struct {
+	conn  Conn
+	opts  Options
+	host  bool
+	local int // this peer's player index
+	state State
+	err   error
+	setup json.RawMessage
+
+	frame     int // the next frame to simulate
+	nextLocal int // the next frame a local input will be scheduled for
+	inputs    [Players]map[int]byte
+	received  int // every remote input before this frame has arrived
+	acked     int // the remote has every local input before this frame
+
+	sums         map[int]uint64 // the host's own checksums
+	remoteSums   map[int]uint64 // the client's checksums the host couldn't check yet
+	needSnapshot bool
+	snapshot     *message // a snapshot the client hasn't applied yet
+
+	peerLeft  bool // the other side has said bye, the frames it had inputs for can still be played
+	lastHeard time.Time
+}
+
+func newSession(conn Conn, opts Options, host bool) *Session {
+	opts.fill()
+	s := &Session{
+		conn:       conn,
+		opts:       opts,
+		host:       host,
+		sums:       map[int]uint64{},
+		remoteSums: map[int]uint64{},
+		lastHeard:  opts.Now(),
+	}
+	for i := range s.inputs {
+		s.inputs[i] = map[int]byte{}
+	}
+	if !host {
+		s.local = 1
+	}
+	return s
+}
+
+// NewHost starts a session on a connection from a Listener. setup is passed
+// on to the client as is, it's whatever the game needs both sides to agree
+// on before the first frame, like the random seed.
+func NewHost(conn Conn, setup []byte, opts Options) *Session {
+	s := newSession(conn, opts, true)
+	s.setup = setup
+	return s
+}
+
+// NewClient joins a host over a dialled connection.
+func NewClient(conn Conn, opts Options) *Session {
+	return newSession(conn, opts, false)
+}
+
+func (s *Session) State() State { return s.state }
+
+// Err is why the session closed.
+func (s *Session) Err() error { return s.err }
+
+// Player is this peer's player index.
+func (s *Session) Player() int { return s.local }
+
+// Setup is the host's setup, on the client it's only there once running.
+func (s *Session) Setup() []byte { return s.setup }
+
+// Frame is the next frame to be simulated.
+func (s *Session) Frame() int { return s.frame }
+
+// start runs once the handshake is done. The first Delay frames have no
+// input from anybody, that's the gap the delay opens up.
+func (s *Session) start(delay int) {
+	s.opts.Delay = delay
+	for f := 0; f < delay; f++ {
+		for p := range s.inputs {
+			s.inputs[p][f] = 0
+		}
+	}
+	s.nextLocal = delay
+	s.received = delay
+	s.acked = delay
+	s.state = StateRunning
+}
+
+// Advance schedules this frame's local input and returns every frame that
+// now has inputs from both players, oldest first. It returns nothing while
+// waiting on the other player, the game should just not step.
+func (s *Session) Advance(local byte) [][Players]byte {
+	s.receive()
+	if s.state == StateConnecting && !s.host {
+		s.send(message{Type: msgHello, Protocol: ProtocolVersion, GameVersion: s.opts.GameVersion})
+	}
+	if s.state != StateRunning {
+		return nil
+	}
+
+	for s.nextLocal <= s.frame+s.opts.Delay {
+		s.inputs[s.local][s.nextLocal] = local
+		s.nextLocal++
+	}
+	if !s.peerLeft {
+		s.sendInputs()
+	}
+
+	var frames [][Players]byte
+	for len(frames) < maxCatchUp {
+		var in [Players]byte
+		ready := true
+		for p := range s.inputs {
+			b, ok := s.inputs[p][s.frame]
+			if !ok {
+				ready = false
+				break
+			}
+			in[p] = b
+		}
+		if !ready {
+			break
+		}
+		frames = append(frames, in)
+		s.frame++
+	}
+	s.prune()
+
+	// once they've gone and everything they sent has been played, that's it
+	if s.peerLeft && len(frames) == 0 {
+		s.fail(ErrPeerLeft)
+	}
+	return frames
+}
+
+// sendInputs sends every local input the other side hasn't confirmed.
+// Repeating them in every message is what makes lost packets harmless.
+func (s *Session) sendInputs() {
+	s.send(s.inputMessage(msgInput))
+}
+
+func (s *Session) inputMessage(kind string) message {
+	start := max(s.acked, s.nextLocal-maxResend)
+	inputs := make([]byte, 0, max(0, s.nextLocal-start))
+	for f := start; f < s.nextLocal; f++ {
+		inputs = append(inputs, s.inputs[s.local][f])
+	}
+	return message{Type: kind, Start: start, Inputs: inputs, Ack: s.received}
+}
+
+func (s *Session) prune() {
+	oldest := s.frame - history
+	for p := range s.inputs {
+		for f := range s.inputs[p] {
+			if f < oldest {
+				delete(s.inputs[p], f)
+			}
+		}
+	}
+	for f := range s.sums {
+		if f < oldest {
+			delete(s.sums, f)
+		}
+	}
+	for f := range s.remoteSums {
+		if f < oldest {
+			delete(s.remoteSums, f)
+		}
+	}
+}
+
+func (s *Session) receive() {
+	if s.state == StateClosed {
+		return
+	}
+	for {
+		data, ok := s.conn.Receive()
+		if !ok {
+			break
+		}
+		var m message
+		if err := json.Unmarshal(data, &m); err != nil {
+			continue // not ours, or mangled on the way
+		}
+		s.lastHeard = s.opts.Now()
+		s.handle(m)
+		if s.state == StateClosed {
+			return
+		}
+	}
+	if !s.peerLeft && s.opts.Now().Sub(s.lastHeard) > s.opts.Timeout {
+		s.fail(ErrTimeout)
+	}
+}
+
+// isHello is true if data is a client's hello, the only thing a listener
+// takes a new peer from.
+func isHello(data []byte) bool {
+	var m message
+	return json.Unmarshal(data, &m) == nil && m.Type == msgHello
+}
+
+func (s *Session) handle(m message) {
+	switch m.Type {
+	case msgHello:
+		if !s.host {
+			return
+		}
+		if m.Protocol != ProtocolVersion || m.GameVersion != s.opts.GameVersion {
+			s.send(message{Type: msgReject, Reason: "the host is running a different version of the game"})
+			return
+		}
+		// repeated hellos mean the welcome got lost, so it's sent again
+		s.send(message{Type: msgWelcome, Protocol: ProtocolVersion, Delay: s.opts.Delay, Setup: s.setup})
+		if s.state == StateConnecting {
+			s.start(s.opts.Delay)
+		}
+
+	case msgWelcome:
+		if s.host || s.state != StateConnecting {
+			return
+		}
+		s.setup = m.Setup
+		s.start(m.Delay)
+
+	case msgReject:
+		s.fail(fmt.Errorf("the host turned us away: %s", m.Reason))
+
+	case msgInput:
+		if s.state != StateRunning {
+			return
+		}
+		s.addRemoteInputs(m)
+
+	case msgChecksum:
+		if !s.host {
+			return
+		}
+		if sum, ok := s.sums[m.Frame]; ok {
+			if sum != m.Checksum {
+				s.needSnapshot = true
+			}
+		} else {
+			s.remoteSums[m.Frame] = m.Checksum
+		}
+
+	case msgSnapshot:
+		if s.host {
+			return
+		}
+		if s.snapshot == nil || m.Frame > s.snapshot.Frame {
+			s.snapshot = &m
+		}
+
+	case msgBye:
+		// the bye has their last inputs in it. At the end of a run the one
+		// that gets there first leaves straight away, the other still has
+		// to play up to the same frame to see it end
+		if s.state != StateRunning {
+			s.fail(ErrPeerLeft)
+			return
+		}
+		s.addRemoteInputs(m)
+		s.peerLeft = true
+	}
+}
+
+func (s *Session) addRemoteInputs(m message) {
+	remote := 1 - s.local
+	for i, b := range m.Inputs {
+		if f := m.Start + i; f >= s.frame-history {
+			s.inputs[remote][f] = b
+		}
+	}
+	for {
+		if _, ok := s.inputs[remote][s.received]; !ok {
+			break
+		}
+		s.received++
+	}
+	s.acked = max(s.acked, m.Ack)
+}
+
+// Checksum records a hash of the game state as it is before frame is
+// simulated. The client sends it to the host, which compares it against
+// its own and asks for a snapshot if they differ.
+func (s *Session) Checksum(frame int, sum uint64) {
+	if s.state != StateRunning {
+		return
+	}
+	if !s.host {
+		s.send(message{Type: msgChecksum, Frame: frame, Checksum: sum})
+		return
+	}
+	s.sums[frame] = sum
+	if remote, ok := s.remoteSums[frame]; ok {
+		delete(s.remoteSums, frame)
+		if remote != sum {
+			s.needSnapshot = true
+		}
+	}
+}
+
+// NeedsSnapshot is true on the host once the client has drifted out of sync.
+func (s *Session) NeedsSnapshot() bool { return s.needSnapshot }
+
+// SendSnapshot sends the client the host's state as it is before Frame().
+func (s *Session) SendSnapshot(state []byte) {
+	s.needSnapshot = false
+	s.send(message{Type: msgSnapshot, Frame: s.frame, State: state})
+}
+
+// TakeSnapshot hands the client a state sent by the host, if there is one.
+// The game must load it before the next Advance, which then carries on from
+// the snapshot's frame.
+func (s *Session) TakeSnapshot() ([]byte, bool) {
+	if s.snapshot == nil {
+		return nil, false
+	}
+	m := s.snapshot
+	s.snapshot = nil
+	s.frame = m.Frame
+	return m.State, true
+}
+
+// Close tells the other player we're leaving, along with our last inputs
+// so they can play on to where we stopped.
+func (s *Session) Close() error {
+	if s.state != StateClosed {
+		bye := s.inputMessage(msgBye)
+		for i := 0; i < byeRepeats; i++ {
+			s.send(bye)
+		}
+		s.state = StateClosed
+		s.err = ErrClosed
+	}
+	return s.conn.Close()
+}
+
+func (s *Session) fail(err error) {
+	s.state = StateClosed
+	s.err = err
+}
+
+func (s *Session) send(m message) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		s.fail(err)
+		return
+	}
+	if len(data) > maxMessage {
+		// it would never arrive, and a lost snapshot leaves the client out of sync for good
+		s.fail(fmt.Errorf("the %s message is %d bytes, more than the %d that can be sent", m.Type, len(data), maxMessage))
+		return
+	}
+	// a failed send is just another lost packet, if the link has really
+	// gone the timeout will notice
+	if err := s.conn.Send(data); errors.Is(err, ErrClosed) {
+		s.fail(err)
+	}
+}
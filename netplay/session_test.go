@@ -0,0 +1,177 @@
+package netplay
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+	"testing"
+	"time"
+)
+
+// clock is a fake time that the tests move on by a frame at a time.
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+// peer is one side of a test game. Its "simulation" hashes every frame's
+// inputs in order, so two peers that played the same frames agree on it.
+type peer struct {
+	session   *Session
+	frames    int
+	state     uint64
+	ended     bool // it played up to the end of the run
+	err       error
+	desyncAt  int // the frame its state goes wrong on, if it's set
+	snapshots int // sent by the host, or loaded by the client
+}
+
+// checksumEvery is how often the peers compare their states, like checksumInterval in the game.
+const checksumEvery = 30
+
+func (p *peer) tick(frame, endAt int) {
+	if p.ended || p.err != nil {
+		return
+	}
+	if state, ok := p.session.TakeSnapshot(); ok {
+		p.state = binary.LittleEndian.Uint64(state)
+		p.frames = p.session.Frame()
+		p.snapshots++
+	}
+	local := byte(frame*7+p.session.Player()) & 0x3f
+	for _, in := range p.session.Advance(local) {
+		h := fnv.New64a()
+		h.Write([]byte{byte(p.state), byte(p.state >> 8), in[0], in[1]})
+		p.state = h.Sum64()
+		p.frames++
+		if p.frames == p.desyncAt {
+			p.state++
+		}
+		if p.frames == endAt {
+			// the run is over, leave straight away like the game does
+			p.ended = true
+			p.session.Close()
+			return
+		}
+		if p.frames%checksumEvery == 0 {
+			p.session.Checksum(p.frames, p.state)
+		}
+	}
+	if p.session.NeedsSnapshot() {
+		p.session.SendSnapshot(binary.LittleEndian.AppendUint64(nil, p.state))
+		p.snapshots++
+	}
+	if p.session.State() == StateClosed {
+		p.err = p.session.Err()
+	}
+}
+
+// play runs a host and a client over a and b until both are done or
+// maxTicks runs out. The host leaves after leaveAt frames if it's set,
+// otherwise both play to endAt. The client's state goes wrong on desyncAt
+// if it's set.
+func play(t *testing.T, a, b Conn, c *clock, endAt, leaveAt, desyncAt int) (*peer, *peer) {
+	t.Helper()
+	opts := Options{GameVersion: 1, Now: c.Now}
+	host := &peer{session: NewHost(a, []byte(`{"seed":1}`), opts)}
+	client := &peer{session: NewClient(b, opts), desyncAt: desyncAt}
+
+	hostEnd := endAt
+	if leaveAt > 0 {
+		hostEnd = leaveAt
+	}
+	for tick := 0; tick < 20*endAt; tick++ {
+		host.tick(tick, hostEnd)
+		client.tick(tick, endAt)
+		c.now = c.now.Add(16 * time.Millisecond)
+		if (host.ended || host.err != nil) && (client.ended || client.err != nil) {
+			break
+		}
+	}
+	return host, client
+}
+
+func TestSessionLoopbackBothReachTheEnd(t *testing.T) {
+	a, b := Pipe()
+	host, client := play(t, a, b, &clock{now: time.Unix(0, 0)}, 300, 0, 0)
+
+	if !host.ended || !client.ended {
+		t.Fatalf("host ended %v (%v), client ended %v (%v)", host.ended, host.err, client.ended, client.err)
+	}
+	if host.state != client.state {
+		t.Fatalf("the peers played different frames")
+	}
+}
+
+func TestSessionLossyLinkBothReachTheEnd(t *testing.T) {
+	for seed := uint64(1); seed <= 20; seed++ {
+		c := &clock{now: time.Unix(0, 0)}
+		a, b := Pipe()
+		lossy := LossyOptions{Latency: 40 * time.Millisecond, Jitter: 30 * time.Millisecond, Loss: 0.2, Now: c.Now}
+		lossy.Seed = seed
+		la := NewLossy(a, lossy)
+		lossy.Seed = seed + 1000
+		lb := NewLossy(b, lossy)
+
+		host, client := play(t, la, lb, c, 300, 0, 0)
+		if !host.ended || !client.ended {
+			t.Fatalf("seed %d: host ended %v (%v), client ended %v (%v)", seed, host.ended, host.err, client.ended, client.err)
+		}
+		if host.state != client.state {
+			t.Fatalf("seed %d: the peers played different frames", seed)
+		}
+	}
+}
+
+func TestSessionPeerLeavingMidRun(t *testing.T) {
+	a, b := Pipe()
+	host, client := play(t, a, b, &clock{now: time.Unix(0, 0)}, 300, 100, 0)
+
+	if !host.ended {
+		t.Fatalf("host didn't leave: %v", host.err)
+	}
+	if client.ended || !errors.Is(client.err, ErrPeerLeft) {
+		t.Fatalf("client ended %v with %v, want it told the host left", client.ended, client.err)
+	}
+	// it still gets to play everything the host played before going
+	if client.frames < host.frames {
+		t.Fatalf("client played %d frames, the host got to %d", client.frames, host.frames)
+	}
+}
+
+func TestSessionResyncsADriftedClient(t *testing.T) {
+	for _, lossy := range []bool{false, true} {
+		c := &clock{now: time.Unix(0, 0)}
+		a, b := Pipe()
+		if lossy {
+			opts := LossyOptions{Latency: 40 * time.Millisecond, Jitter: 30 * time.Millisecond, Loss: 0.2, Now: c.Now, Seed: 1}
+			a = NewLossy(a, opts)
+			opts.Seed = 2
+			b = NewLossy(b, opts)
+		}
+		host, client := play(t, a, b, c, 600, 0, 100)
+
+		if !host.ended || !client.ended {
+			t.Fatalf("lossy %v: host ended %v (%v), client ended %v (%v)", lossy, host.ended, host.err, client.ended, client.err)
+		}
+		if host.snapshots == 0 || client.snapshots == 0 {
+			t.Fatalf("lossy %v: host sent %d snapshots, client loaded %d", lossy, host.snapshots, client.snapshots)
+		}
+		if host.state != client.state {
+			t.Fatalf("lossy %v: the client never got back in sync", lossy)
+		}
+	}
+}
+
+func TestSessionRefusesOversizedSnapshots(t *testing.T) {
+	a, b := Pipe()
+	s := NewHost(a, nil, Options{GameVersion: 1})
+	NewClient(b, Options{GameVersion: 1}).Advance(0)
+	s.Advance(0)
+	if s.State() != StateRunning {
+		t.Fatalf("the host never started")
+	}
+	s.SendSnapshot(make([]byte, maxMessage))
+	if s.State() != StateClosed || s.Err() == nil || errors.Is(s.Err(), ErrClosed) {
+		t.Fatalf("an oversized snapshot left the session %v with %v", s.State(), s.Err())
+	}
+}
@@ -0,0 +1,124 @@
+//go:build !js
+
+package netplay
+
+import (
+	"net"
+	"sync"
+)
+
+type udpConn struct {
+	*inbox
+	pc     net.PacketConn
+	remote net.Addr
+}
+
+// DialUDP connects to a game hosted with Listen.
+func DialUDP(addr string) (Conn, error) {
+	remote, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	pc, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &udpConn{inbox: newInbox(), pc: pc, remote: remote}
+	go func() {
+		buf := make([]byte, maxMessage)
+		for {
+			n, from, err := pc.ReadFrom(buf)
+			if err != nil {
+				c.close()
+				return
+			}
+			if from.String() == remote.String() {
+				c.push(append([]byte(nil), buf[:n]...))
+			}
+		}
+	}()
+	return c, nil
+}
+
+func (c *udpConn) Send(msg []byte) error {
+	if c.isClosed() {
+		return ErrClosed
+	}
+	_, err := c.pc.WriteTo(msg, c.remote)
+	return err
+}
+
+func (c *udpConn) Close() error {
+	c.close()
+	return c.pc.Close()
+}
+
+// udpListener hands out a Conn for the first address that says hello.
+// It's a two player game, anyone else is ignored. The accepted Conn takes
+// over the socket, closing the listener after that leaves it alone.
+type udpListener struct {
+	pc       net.PacketConn
+	accepted chan Conn
+
+	mu   sync.Mutex
+	peer *udpConn
+}
+
+func listenUDP(addr string) (*udpListener, error) {
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &udpListener{pc: pc, accepted: make(chan Conn, 1)}
+	go func() {
+		buf := make([]byte, maxMessage)
+		for {
+			n, from, err := pc.ReadFrom(buf)
+			if err != nil {
+				l.mu.Lock()
+				if l.peer != nil {
+					l.peer.close()
+				}
+				l.mu.Unlock()
+				return
+			}
+			msg := append([]byte(nil), buf[:n]...)
+
+			l.mu.Lock()
+			// stray packets and port scans mustn't take the place of the real player
+			if l.peer == nil && isHello(msg) {
+				l.peer = &udpConn{inbox: newInbox(), pc: pc, remote: from}
+				l.accepted <- l.peer
+			}
+			if l.peer != nil && from.String() == l.peer.remote.String() {
+				l.peer.push(msg)
+			}
+			l.mu.Unlock()
+		}
+	}()
+	return l, nil
+}
+
+func (l *udpListener) Accept() (Conn, bool) {
+	select {
+	case c := <-l.accepted:
+		return c, true
+	default:
+		return nil, false
+	}
+}
+
+func (l *udpListener) Addr() string {
+	return l.pc.LocalAddr().String()
+}
+
+func (l *udpListener) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.peer != nil {
+		return nil
+	}
+	return l.pc.Close()
+}
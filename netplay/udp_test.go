@@ -0,0 +1,45 @@
+//go:build !js
+
+package netplay
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestUDPListenerWaitsForAHello(t *testing.T) {
+	l, err := listenUDP("127.0.0.1:0")
+	if err != nil {
+		t.Skipf("no UDP here: %v", err)
+	}
+	defer l.Close()
+
+	stray, err := net.Dial("udp", l.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stray.Close()
+	stray.Write([]byte("not a game"))
+	stray.Write([]byte(`{"type":"input"}`))
+
+	player, err := DialUDP(l.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer player.Close()
+	NewClient(player, Options{GameVersion: 1}).Advance(0) // says hello
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if conn, ok := l.Accept(); ok {
+			got := conn.(*udpConn).remote.(*net.UDPAddr).Port
+			if want := player.(*udpConn).pc.LocalAddr().(*net.UDPAddr).Port; got != want {
+				t.Fatalf("took port %d as the peer, the player is on %d", got, want)
+			}
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("never accepted the player")
+}
@@ -0,0 +1,113 @@
+//go:build !js
+
+package netplay
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsConn is a WebSocket connection from the desktop side, either one a
+// browser opened to a hosted game or one we dialled ourselves.
+type wsConn struct {
+	*inbox
+	ws *websocket.Conn
+	mu sync.Mutex // gorilla allows one writer at a time
+}
+
+func newWSConn(ws *websocket.Conn) *wsConn {
+	c := &wsConn{inbox: newInbox(), ws: ws}
+	go func() {
+		for {
+			_, msg, err := ws.ReadMessage()
+			if err != nil {
+				c.close()
+				return
+			}
+			c.push(msg)
+		}
+	}()
+	return c
+}
+
+// DialWebSocket connects to a game hosted with Listen, url is like ws://host:7777/.
+func DialWebSocket(url string) (Conn, error) {
+	ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return newWSConn(ws), nil
+}
+
+func (c *wsConn) Send(msg []byte) error {
+	if c.isClosed() {
+		return ErrClosed
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ws.WriteMessage(websocket.BinaryMessage, msg)
+}
+
+func (c *wsConn) Close() error {
+	c.close()
+	return c.ws.Close()
+}
+
+// wsListener serves WebSocket upgrades so the browser build can join a desktop host.
+type wsListener struct {
+	ln       net.Listener
+	server   *http.Server
+	accepted chan Conn
+}
+
+func listenWebSocket(addr string) (*wsListener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &wsListener{ln: ln, accepted: make(chan Conn, 1)}
+	upgrader := websocket.Upgrader{
+		// the browser build is usually served from somewhere else, e.g. itch.io
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}
+	l.server = &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return // Upgrade has already written the error response
+		}
+		select {
+		case l.accepted <- newWSConn(ws):
+		default:
+			ws.Close() // someone else got there first
+		}
+	})}
+	go func() {
+		if err := l.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			ln.Close()
+		}
+	}()
+	return l, nil
+}
+
+func (l *wsListener) Accept() (Conn, bool) {
+	select {
+	case c := <-l.accepted:
+		return c, true
+	default:
+		return nil, false
+	}
+}
+
+func (l *wsListener) Addr() string {
+	return l.ln.Addr().String()
+}
+
+// Close stops taking new connections, ones already accepted stay open.
+func (l *wsListener) Close() error {
+	return l.server.Close()
+}
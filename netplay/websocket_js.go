@@ -0,0 +1,89 @@
+//go:build js
+
+package netplay
+
+import (
+	"errors"
+	"strings"
+	"syscall/js"
+)
+
+// wsConn is the browser's own WebSocket, driven through syscall/js.
+type wsConn struct {
+	*inbox
+	ws        js.Value
+	callbacks []js.Func
+}
+
+// Dial joins a hosted game. Browsers can only speak WebSocket, so a bare
+// host:port is taken to mean ws://host:port/.
+func Dial(addr string) (Conn, error) {
+	if !strings.HasPrefix(addr, "ws://") && !strings.HasPrefix(addr, "wss://") {
+		addr = "ws://" + addr + "/"
+	}
+	return DialWebSocket(addr)
+}
+
+func DialWebSocket(url string) (conn Conn, err error) {
+	defer func() {
+		// the constructor throws on a malformed url
+		if r := recover(); r != nil {
+			err = errors.New("connecting to " + url + ": invalid address")
+		}
+	}()
+
+	ws := js.Global().Get("WebSocket").New(url)
+	ws.Set("binaryType", "arraybuffer")
+	c := &wsConn{inbox: newInbox(), ws: ws}
+
+	onMessage := js.FuncOf(func(this js.Value, args []js.Value) any {
+		data := js.Global().Get("Uint8Array").New(args[0].Get("data"))
+		msg := make([]byte, data.Get("length").Int())
+		js.CopyBytesToGo(msg, data)
+		c.push(msg)
+		return nil
+	})
+	onClose := js.FuncOf(func(this js.Value, args []js.Value) any {
+		c.close()
+		return nil
+	})
+	ws.Set("onmessage", onMessage)
+	ws.Set("onclose", onClose)
+	ws.Set("onerror", onClose)
+	c.callbacks = []js.Func{onMessage, onClose}
+	return c, nil
+}
+
+// Send drops messages while the socket is still opening, the session
+// keeps repeating itself until it hears back so nothing is lost for good.
+func (c *wsConn) Send(msg []byte) error {
+	if c.isClosed() {
+		return ErrClosed
+	}
+	if c.ws.Get("readyState").Int() != 1 { // OPEN
+		return nil
+	}
+	data := js.Global().Get("Uint8Array").New(len(msg))
+	js.CopyBytesToJS(data, msg)
+	c.ws.Call("send", data)
+	return nil
+}
+
+func (c *wsConn) Close() error {
+	c.close()
+	// unhook first, the close event arrives later and the callbacks will be gone
+	for _, name := range []string{"onmessage", "onclose", "onerror"} {
+		c.ws.Set(name, js.Null())
+	}
+	c.ws.Call("close")
+	for _, f := range c.callbacks {
+		f.Release()
+	}
+	c.callbacks = nil
+	return nil
+}
+
+// Listen isn't possible from a browser, it can only join a desktop host.
+func Listen(addr string) (Listener, error) {
+	return nil, errors.New("games can't be hosted from the browser, host from the desktop build and join it")
+}
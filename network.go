@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+
+	"github.com/stuartstein777/go-space-shooter/netplay"
+)
+
+// netVersion goes up with any change to the simulation, two different
+// builds would drift apart so they refuse to play each other.
+const netVersion = 1
+
+const defaultNetAddress = ":7777"
+
+// checksumInterval is how often, in frames, the client checks it's still in sync with the host.
+const checksumInterval = 60
+
+// networkGame is a run with someone on another machine. The host is
+// always player one and the client player two.
+type networkGame struct {
+	listener netplay.Listener // only while the host waits for someone to join
+	session  *netplay.Session
+	mode     GameMode // what the host asked for, the client finds out from the setup
+	started  bool
+}
+
+func (g *Game) hostGame(addr string, mode GameMode) {
+	listener, err := netplay.Listen(addr)
+	if err != nil {
+		g.netStatus = fmt.Sprintf("Couldn't host: %v", err)
+		return
+	}
+	g.network = &networkGame{listener: listener, mode: mode}
+	g.netStatus = "Waiting for someone to join on " + listener.Addr()
+	g.menus = nil // rebuilds the title menu with just Cancel
+}
+
+func (g *Game) joinGame(addr string) {
+	conn, err := netplay.Dial(addr)
+	if err != nil {
+		g.netStatus = fmt.Sprintf("Couldn't join %s: %v", addr, err)
+		return
+	}
+	g.network = &networkGame{session: netplay.NewClient(conn, netplay.Options{GameVersion: netVersion})}
+	g.netStatus = "Joining " + addr
+	g.menus = nil
+}
+
+// leaveNetworkGame hangs up and goes back to the title screen with status shown on it.
+func (g *Game) leaveNetworkGame(status string) {
+	n := g.network
+	if n == nil {
+		return
+	}
+	if n.listener != nil {
+		n.listener.Close()
+	}
+	if n.session != nil {
+		n.session.Close()
+	}
+	g.network = nil
+	g.netStatus = status
+	if !g.showSplash {
+		g.Reset()
+		g.previousScores = nil
+	}
+	g.menus = nil
+}
+
+func (g *Game) startNetworkGame(data []byte) error {
+	var cfg RunConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("reading the host's setup: %w", err)
+	}
+	cfg.Players = netplay.Players
+	g.newRun(cfg)
+	g.replay = nil // it's checksummed with the rest of the state every second, and never submitted
+	g.previousScores = nil
+	g.network.started = true
+	return nil
+}
+
+// updateNetwork replaces the normal Update while a network game is on.
+// There's no pausing, the other player's game carries on, so the menus are
+// drawn over the top while our ship sits still.
+func (g *Game) updateNetwork() {
+	n := g.network
+
+	if n.session == nil {
+		if conn, ok := n.listener.Accept(); ok {
+			n.listener.Close()
+			n.listener = nil
+			setup, err := json.Marshal(g.newRunConfig(n.mode, netplay.Players))
+			if err != nil {
+				conn.Close()
+				g.leaveNetworkGame(err.Error())
+				return
+			}
+			n.session = netplay.NewHost(conn, setup, netplay.Options{GameVersion: netVersion})
+		}
+	}
+
+	var local PlayerInput
+	if g.showSplash || len(g.menus) > 0 {
+		g.updateMenus()
+		if g.network == nil {
+			return // left from a menu
+		}
+	} else if pausePressed() || g.touch.pausePressed() {
+		g.openMenu(newNetworkMenu())
+	} else {
+		local = readPlayerInput(g.settings.PlayerKeys[0], g.settings.AutoFire)
+		if n.session != nil && n.session.Player() < len(g.players) {
+			local = g.withTouch(g.players[n.session.Player()], local)
+		}
+	}
+
+	if n.started {
+		g.particles.Update()
+		g.popups.Update()
+		g.effects.Update() // hit-stop and slow motion are only for show here, both games have to keep stepping
+	}
+	if n.session == nil {
+		return
+	}
+
+	if state, ok := n.session.TakeSnapshot(); ok {
+		if err := g.loadNetworkSnapshot(state); err != nil {
+			log.Printf("loading the host's snapshot: %v", err)
+		}
+	}
+
+	frames := n.session.Advance(local.bits())
+	if n.session.State() == netplay.StateClosed {
+		g.leaveNetworkGame(n.session.Err().Error())
+		return
+	}
+	if !n.started && n.session.State() == netplay.StateRunning {
+		if err := g.startNetworkGame(n.session.Setup()); err != nil {
+			g.leaveNetworkGame(err.Error())
+			return
+		}
+	}
+
+	// Advance has already counted these frames, so work out where they start
+	next := n.session.Frame() - len(frames)
+	for _, frame := range frames {
+		inputs := make([]PlayerInput, len(frame))
+		for i, b := range frame {
+			inputs[i] = inputFromBits(b)
+		}
+		g.step(inputs)
+
+		// both machines get to the end of the run on the same frame
+		if g.showSplash {
+			g.leaveNetworkGame("")
+			return
+		}
+		next++
+		if next%checksumInterval == 0 {
+			n.session.Checksum(next, g.checksum())
+		}
+	}
+
+	if n.session.NeedsSnapshot() {
+		state, err := encodeSave(g)
+		if err != nil {
+			log.Printf("sending a snapshot: %v", err)
+			return
+		}
+		n.session.SendSnapshot(state)
+	}
+}
+
+// checksum hashes everything the simulation depends on, the same thing a save holds.
+func (g *Game) checksum() uint64 {
+	data, err := encodeSave(g)
+	if err != nil {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write(data)
+	return h.Sum64()
+}
+
+func (g *Game) loadNetworkSnapshot(state []byte) error {
+	s, err := decodeSave(state)
+	if err != nil {
+		return err
+	}
+	if err := g.restore(s); err != nil {
+		return err
+	}
+	g.showSplash = false
+	return nil
+}
+
+// newNetworkMenu stands in for the pause menu, it can't stop the game.
+func newNetworkMenu() *Menu {
+	return &Menu{
+		title: "NETWORK GAME",
+		items: []menuItem{
+			buttonItem("Resume", func(g *Game) { g.closeMenu() }),
+			buttonItem("Settings", func(g *Game) { g.openMenu(newSettingsMenu()) }),
+			buttonItem("Leave game", func(g *Game) { g.leaveNetworkGame("You left the game") }),
+		},
+	}
+}
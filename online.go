@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/stuartstein777/go-space-shooter/leaderboard"
+)
+
+// leaderboardKey signs submissions. Anyone with the game can dig it out, so
+// it only stops scores being edited on the way, the server checking the
+// replay is what really keeps the board honest.
+const leaderboardKey = "space-shooter/leaderboard/v1"
+
+// leaderboardQueueFile holds scores that haven't reached the server yet.
+const leaderboardQueueFile = "leaderboard-queue.json"
+
+// onlineLeaderboard is only set up when the settings have a leaderboard URL.
+// Everything talking to the server runs in the background, the results are
+// picked up by the menus and title screen through mu.
+type onlineLeaderboard struct {
+	board leaderboard.Board
+	queue *leaderboard.Queue
+
+	mu        sync.Mutex
+	lastEntry *leaderboard.Entry // where the last score we sent ended up
+	top       []leaderboard.Entry
+	around    []leaderboard.Entry
+	status    string
+}
+
+func (g *Game) setupLeaderboard() {
+	if g.settings.LeaderboardURL == "" {
+		return
+	}
+	board := leaderboard.NewClient(g.settings.LeaderboardURL)
+	o := &onlineLeaderboard{board: board}
+
+	var pending []leaderboard.Submission
+	if data, err := readStorage(leaderboardQueueFile); err == nil {
+		if err := json.Unmarshal(data, &pending); err != nil {
+			log.Printf("reading unsent scores: %v", err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("reading unsent scores: %v", err)
+	}
+
+	o.queue = leaderboard.NewQueue(board, pending)
+	o.queue.OnChange = func(pending []leaderboard.Submission) {
+		data, err := json.Marshal(pending)
+		if err == nil {
+			err = writeStorage(leaderboardQueueFile, data)
+		}
+		if err != nil {
+			log.Printf("saving unsent scores: %v", err)
+		}
+	}
+	o.queue.OnSubmitted = func(e leaderboard.Entry) {
+		o.mu.Lock()
+		o.lastEntry = &e
+		o.mu.Unlock()
+	}
+	go o.queue.Run(context.Background())
+	g.online = o
+}
+
+// boardName is which leaderboard a run goes on, there's one per difficulty.
+func boardName(d Difficulty) string {
+	return strings.ToLower(d.tuning().Name)
+}
+
+// dailyBoard is the leaderboard for one day's daily challenge.
+func dailyBoard(date string) string {
+	return "daily-" + date
+}
+
+// runBoard is the leaderboard a run with cfg goes on.
+func runBoard(cfg RunConfig) string {
+	if cfg.Daily != "" {
+		return dailyBoard(cfg.Daily)
+	}
+	return boardName(cfg.Difficulty)
+}
+
+// submitScore queues a finished solo run for the leaderboard. Co-op,
+// versus, network games and daily practice don't count.
+func (g *Game) submitScore() {
+	if g.online == nil || g.replay == nil || g.network != nil ||
+		g.mode != modeSurvival || len(g.players) != 1 || g.practice {
+		return
+	}
+	s := leaderboard.Submission{
+		Board:      runBoard(g.replay.Config),
+		Name:       g.settings.PlayerName,
+		Score:      g.players[0].Score,
+		Frames:     g.replay.Frames(),
+		Seed1:      g.replay.Config.Seed1,
+		Seed2:      g.replay.Config.Seed2,
+		ReplayHash: g.replay.Hash(),
+		StateHash:  g.finalState,
+	}
+	s.Replay, _ = g.replay.Encode()
+	s.Sign([]byte(leaderboardKey))
+
+	g.online.mu.Lock()
+	g.online.lastEntry = nil
+	g.online.mu.Unlock()
+	g.online.queue.Add(s)
+}
+
+// rankText is shown on the game over screen once the server has replied.
+func (o *onlineLeaderboard) rankText() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.lastEntry == nil {
+		return ""
+	}
+	return fmt.Sprintf("Rank #%d on the %s leaderboard", o.lastEntry.Rank, o.lastEntry.Board)
+}
+
+// fetch loads the top of the board and the bit around the player, for the leaderboard menu.
+func (o *onlineLeaderboard) fetch(board, name string) {
+	o.mu.Lock()
+	o.status = "Loading..."
+	o.top, o.around = nil, nil
+	o.mu.Unlock()
+
+	go func() {
+		ctx := context.Background()
+		top, err := o.board.Top(ctx, board, 10)
+		around, aroundErr := o.board.Around(ctx, board, name, 5)
+
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		o.status = ""
+		if err != nil {
+			o.status = "Couldn't reach the leaderboard"
+			return
+		}
+		o.top = top
+		if aroundErr == nil {
+			o.around = around
+		}
+	}()
+}
+
+func newLeaderboardMenu(g *Game, board string) *Menu {
+	g.online.fetch(board, g.settings.PlayerName)
+
+	line := func(list func(o *onlineLeaderboard) []leaderboard.Entry, i int) func(g *Game) string {
+		return func(g *Game) string {
+			o := g.online
+			o.mu.Lock()
+			defer o.mu.Unlock()
+			if i == 0 && o.status != "" {
+				return o.status
+			}
+			entries := list(o)
+			if i >= len(entries) {
+				return "-"
+			}
+			e := entries[i]
+			return fmt.Sprintf("%d. %s  %d", e.Rank, e.Name, e.Score)
+		}
+	}
+
+	m := &Menu{title: "LEADERBOARD - " + strings.ToUpper(board)}
+	for i := 0; i < 10; i++ {
+		m.items = append(m.items, menuItem{label: line(func(o *onlineLeaderboard) []leaderboard.Entry { return o.top }, i)})
+	}
+	m.items = append(m.items, menuItem{label: func(g *Game) string { return "Around " + g.settings.PlayerName + ":" }})
+	for i := 0; i < 5; i++ {
+		m.items = append(m.items, menuItem{label: line(func(o *onlineLeaderboard) []leaderboard.Entry { return o.around }, i)})
+	}
+	m.items = append(m.items, backItem())
+	m.selected = len(m.items) - 1
+	return m
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"math/rand/v2"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const defaultParticleBudget = 2000
+
+// ParticleDetail is the particles setting, how many can be alive at once.
+type ParticleDetail int
+
+const (
+	particlesLow ParticleDetail = iota
+	particlesNormal
+	particlesHigh
+)
+
+var particleDetails = []struct {
+	Name   string
+	Budget int
+}{
+	particlesLow:    {Name: "Low", Budget: 500},
+	particlesNormal: {Name: "Normal", Budget: defaultParticleBudget},
+	particlesHigh:   {Name: "High", Budget: 6000},
+}
+
+func (d ParticleDetail) budget() int {
+	if int(d) >= 0 && int(d) < len(particleDetails) {
+		return particleDetails[d].Budget
+	}
+	return defaultParticleBudget
+}
+
+// maxParticlesPerBatch is how many quads go in one DrawTriangles call. Each
+// has 4 vertices, so this many is as far as uint16 indices reach.
+const maxParticlesPerBatch = 1 << 14
+
+type Particle struct {
+	X, Y    float64
+	VX, VY  float64
+	Size    float64
+	Drag    float64 // fraction of velocity kept each frame, 1 means no slow down
+	Life    int     // frames left
+	MaxLife int
+	Colour  color.RGBA
+}
+
+type ParticleSystem struct {
+	particles []Particle
+	Budget    int // the most particles alive at once, extra emissions are dropped
+
+	vertices []ebiten.Vertex
+	indices  []uint16
+}
+
+// emit adds a particle unless we are already at the budget.
+// Particles are purely cosmetic, so they use the global rand rather than
+// anything that affects the game simulation.
+func (ps *ParticleSystem) emit(p Particle) {
+	budget := ps.Budget
+	if budget <= 0 {
+		budget = defaultParticleBudget
+	}
+	if len(ps.particles) >= budget {
+		return
+	}
+	if p.Drag == 0 {
+		p.Drag = 1
+	}
+	p.MaxLife = p.Life
+	ps.particles = append(ps.particles, p)
+}
+
+func (ps *ParticleSystem) Update() {
+	alive := ps.particles[:0]
+	for _, p := range ps.particles {
+		p.Life--
+		if p.Life <= 0 {
+			continue
+		}
+		p.X += p.VX
+		p.Y += p.VY
+		p.VX *= p.Drag
+		p.VY *= p.Drag
+		alive = append(alive, p)
+	}
+	ps.particles = alive
+}
+
+func (ps *ParticleSystem) Clear() {
+	ps.particles = ps.particles[:0]
+}
+
+// emitBurst throws count particles out from x, y in random directions.
+func (ps *ParticleSystem) emitBurst(x, y float64, count int, minSpeed, maxSpeed, size float64, life int, colour color.RGBA) {
+	for i := 0; i < count; i++ {
+		angle := rand.Float64() * 2 * math.Pi
+		speed := minSpeed + rand.Float64()*(maxSpeed-minSpeed)
+		ps.emit(Particle{
+			X:      x,
+			Y:      y,
+			VX:     math.Cos(angle) * speed,
+			VY:     math.Sin(angle) * speed,
+			Size:   size * (0.5 + rand.Float64()),
+			Drag:   0.95,
+			Life:   life/2 + rand.IntN(life/2+1),
+			Colour: colour,
+		})
+	}
+}
+
+// EmitExplosion scales the number, speed and size of the debris by the enemy's radius.
+func (ps *ParticleSystem) EmitExplosion(x, y, radius float64) {
+	scale := radius / 40
+	ps.emitBurst(x, y, int(10+30*scale), 1, 2+4*scale, 2+2*scale, 50, color.RGBA{255, 200, 0, 255})
+	ps.emitBurst(x, y, int(5+15*scale), 0.5, 1+2*scale, 3+3*scale, 70, color.RGBA{255, 80, 0, 255})
+}
+
+// EmitExhaust puts a puff of flame out of the back of the ship.
+func (ps *ParticleSystem) EmitExhaust(p *Player) {
+	// back of the ship, the bottom corner of the diamond
+	back := p.shipPolygon()[2]
+	backX, backY := back[0], back[1]
+
+	sin, cos := math.Sin(p.ShipAngle), math.Cos(p.ShipAngle)
+	for i := 0; i < 2; i++ {
+		spread := (rand.Float64() - 0.5) * 0.6
+		speed := 2 + rand.Float64()*2
+		ps.emit(Particle{
+			X:      backX,
+			Y:      backY,
+			VX:     -math.Sin(p.ShipAngle+spread)*speed + p.Velocity*sin,
+			VY:     math.Cos(p.ShipAngle+spread)*speed - p.Velocity*cos,
+			Size:   2 + rand.Float64()*2,
+			Drag:   0.9,
+			Life:   15 + rand.IntN(10),
+			Colour: color.RGBA{255, 140, 40, 255},
+		})
+	}
+}
+
+// EmitSparks is the small flash where a bullet hits something.
+func (ps *ParticleSystem) EmitSparks(x, y float64) {
+	ps.emitBurst(x, y, 8, 1, 4, 1.5, 16, color.RGBA{200, 255, 200, 255})
+}
+
+// EmitShockwave sends a ring of particles out from the bomb.
+func (ps *ParticleSystem) EmitShockwave(x, y float64) {
+	const count = 120
+	for i := 0; i < count; i++ {
+		angle := 2 * math.Pi * float64(i) / count
+		speed := 12 + rand.Float64()*2
+		ps.emit(Particle{
+			X:      x,
+			Y:      y,
+			VX:     math.Cos(angle) * speed,
+			VY:     math.Sin(angle) * speed,
+			Size:   4,
+			Drag:   0.98,
+			Life:   60,
+			Colour: color.RGBA{180, 220, 255, 255},
+		})
+	}
+}
+
+// EmitPickup is a little sparkle in the colour of the powerup that was collected.
+func (ps *ParticleSystem) EmitPickup(x, y float64, powerupType int) {
+	ps.emitBurst(x, y, 24, 1, 3, 2, 30, powerupInfo(powerupType).Colour)
+}
+
+// Draw renders every particle as a quad, batched so it's one DrawTriangles
+// call per maxParticlesPerBatch particles rather than one per particle.
+func (ps *ParticleSystem) Draw(screen *ebiten.Image) {
+	for start := 0; start < len(ps.particles); start += maxParticlesPerBatch {
+		end := min(start+maxParticlesPerBatch, len(ps.particles))
+
+		ps.vertices = ps.vertices[:0]
+		ps.indices = ps.indices[:0]
+
+		for i, p := range ps.particles[start:end] {
+			fade := float32(p.Life) / float32(p.MaxLife)
+			r, g, b, a := colorToFloats(p.Colour)
+			a *= fade
+			half := float32(p.Size / 2)
+			x, y := float32(p.X), float32(p.Y)
+
+			for _, corner := range [4][2]float32{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}} {
+				ps.vertices = append(ps.vertices, ebiten.Vertex{
+					DstX:   x + corner[0]*half,
+					DstY:   y + corner[1]*half,
+					SrcX:   0,
+					SrcY:   0,
+					ColorR: r, ColorG: g, ColorB: b, ColorA: a,
+				})
+			}
+
+			base := uint16(i * 4)
+			ps.indices = append(ps.indices, base, base+1, base+2, base, base+2, base+3)
+		}
+
+		op := &ebiten.DrawTrianglesOptions{}
+		op.Blend = ebiten.BlendLighter
+		screen.DrawTriangles(ps.vertices, ps.indices, whiteImg, op)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"math"
+)
+
+const maxPlayers = 2
+
+// Player is everything that belongs to one ship. The enemy field, the
+// anomaly and the enemy wide powerups (freeze, invincible enemies) are shared
+// and stay on Game.
+type Player struct {
+	ID            int           `json:"id"` // index into Game.players and the settings' PlayerKeys
+	Location      Point         `json:"location"`
+	ShipAngle     float64       `json:"shipAngle"` // in radians
+	Velocity      float64       `json:"velocity"`
+	MaxSpeed      float64       `json:"maxSpeed"`
+	ShootCooldown int           `json:"shootCooldown"`
+	Score         int           `json:"score"`
+	Bombs         int           `json:"bombs"`
+	BombCapacity  int           `json:"bombCapacity"` // the most bombs it can carry
+	Effects       ActiveEffects `json:"effects"`      // the timed powerups on this ship
+	Alive         bool          `json:"alive"`
+	FramesAlive   int           `json:"framesAlive"`
+	Combo         int           `json:"combo"`        // kills chained without a break
+	ComboTimer    int           `json:"comboTimer"`   // frames left to keep the chain going
+	Lives         int           `json:"lives"`        // spare ships, the next hit with none left is fatal
+	Invulnerable  int           `json:"invulnerable"` // frames left that nothing can hurt it, after losing a life
+	DroneCooldown int           `json:"droneCooldown"`
+	BombHeld      bool          `json:"bombHeld"`   // the bomb key was down last frame
+	FireBuffer    int           `json:"fireBuffer"` // frames left that a shot that couldn't happen yet still will
+	BombBuffer    int           `json:"bombBuffer"` // the same for a bomb
+}
+
+// PlayerInput is what one player is asking their ship to do this frame.
+// The simulation only ever looks at these, never the keyboard, so the same
+// frame can be fed from a keyboard, a replay or the network.
+type PlayerInput struct {
+	RotateLeft  bool `json:"l,omitempty"`
+	RotateRight bool `json:"r,omitempty"`
+	Thrust      bool `json:"t,omitempty"`
+	Brake       bool `json:"b,omitempty"`
+	Fire        bool `json:"f,omitempty"`
+	Bomb        bool `json:"x,omitempty"`
+}
+
+// readPlayerInput turns the keys into this frame's input. Movement is
+// whatever is held down, the bomb only on the frame its key goes down, and
+// shooting is the same unless autoFire lets it be held.
+func readPlayerInput(keys KeyBindings, autoFire bool) PlayerInput {
+	fire := keys.state(actionFire)
+	return PlayerInput{
+		RotateLeft:  keys.pressed(actionRotateLeft),
+		RotateRight: keys.pressed(actionRotateRight),
+		Thrust:      keys.pressed(actionThrust),
+		Brake:       keys.pressed(actionBrake),
+		Fire:        fire.JustPressed || (autoFire && fire.Pressed),
+		Bomb:        keys.state(actionBomb).JustPressed,
+	}
+}
+
+// bits packs the input into a byte for sending over the network.
+func (in PlayerInput) bits() byte {
+	var b byte
+	for i, pressed := range []bool{in.RotateLeft, in.RotateRight, in.Thrust, in.Brake, in.Fire, in.Bomb} {
+		if pressed {
+			b |= 1 << i
+		}
+	}
+	return b
+}
+
+func inputFromBits(b byte) PlayerInput {
+	return PlayerInput{
+		RotateLeft:  b&(1<<0) != 0,
+		RotateRight: b&(1<<1) != 0,
+		Thrust:      b&(1<<2) != 0,
+		Brake:       b&(1<<3) != 0,
+		Fire:        b&(1<<4) != 0,
+		Bomb:        b&(1<<5) != 0,
+	}
+}
+
+// newPlayer puts the ship in the middle of the screen, or side by side for co-op.
+func newPlayer(id, count, lives int) *Player {
+	x := 640
+	if count > 1 {
+		x = 640 - 160 + 320*id
+	}
+	return &Player{
+		ID:            id,
+		Location:      Point{X: x, Y: 480},
+		MaxSpeed:      20, // adjust as desired
+		ShootCooldown: bulletCooldown,
+		Alive:         true,
+		Lives:         lives,
+		BombCapacity:  baseBombCapacity,
+	}
+}
+
+func (g *Game) alivePlayers() int {
+	n := 0
+	for _, p := range g.players {
+		if p.Alive {
+			n++
+		}
+	}
+	return n
+}
+
+// totalScore is what the anomaly milestones are measured against in co-op.
+func (g *Game) totalScore() int {
+	total := 0
+	for _, p := range g.players {
+		total += p.Score
+	}
+	return total
+}
+
+// shipPolygon is the four corners of the ship's diamond, rotated to the way it's facing.
+// DrawShip and the collision checks both use it so what you see is what gets hit.
+func (p *Player) shipPolygon() [][2]float64 {
+	cx := float64(p.Location.X)
+	cy := float64(p.Location.Y)
+	shipHeight := 75.0
+	shipWidth := 30.0
+	angle := p.ShipAngle
+
+	topX, topY := cx, cy-shipHeight/2
+	rightX, rightY := cx+shipWidth/2, cy
+	bottomX, bottomY := cx, cy+shipHeight/4
+	leftX, leftY := cx-shipWidth/2, cy
+
+	topX, topY = RotatePoint(topX, topY, cx, cy, angle)
+	rightX, rightY = RotatePoint(rightX, rightY, cx, cy, angle)
+	bottomX, bottomY = RotatePoint(bottomX, bottomY, cx, cy, angle)
+	leftX, leftY = RotatePoint(leftX, leftY, cx, cy, angle)
+
+	return [][2]float64{
+		{topX, topY},
+		{rightX, rightY},
+		{bottomX, bottomY},
+		{leftX, leftY},
+	}
+}
+
+// tickTimers counts down the player's own timers, powerups are done by tickEffects.
+func (p *Player) tickTimers() {
+	if p.Invulnerable > 0 {
+		p.Invulnerable--
+	}
+	if p.FireBuffer > 0 {
+		p.FireBuffer--
+	}
+	if p.BombBuffer > 0 {
+		p.BombBuffer--
+	}
+
+	if p.Alive {
+		p.FramesAlive++
+	}
+	p.tickCombo()
+}
+
+// killPlayer takes a ship out of the run. The run is over when nobody is left.
+func (g *Game) killPlayer(p *Player, cause deathCause, b *Bullet) {
+	p.Alive = false
+	p.breakCombo()
+	g.events.playerDied.publish(PlayerDied{Player: p, Cause: cause, Bullet: b})
+}
+
+// endRun is called by the simulation when the last ship has gone.
+func (g *Game) endRun() {
+	g.previousScores = g.scores()
+	g.finalState = g.stateHash()
+	if g.headless {
+		return
+	}
+	g.finishStats()
+	g.saveReplay()
+	g.finishDaily()
+	g.submitScore()
+	if g.network == nil {
+		clearAutosave() // network games are never autosaved, don't lose a local one
+	}
+}
+
+func (g *Game) scores() []int {
+	scores := make([]int, len(g.players))
+	for i, p := range g.players {
+		scores[i] = p.Score
+	}
+	return scores
+}
+
+// bulletHitsPlayer is the friendly fire check, any bullet not fired by the
+// ship itself can hit it. Whether it hurts is up to damagePlayer.
+func bulletHitsPlayer(b *Bullet, p *Player) bool {
+	if b.Owner == p.ID || !p.Alive {
+		return false
+	}
+	return polygonCircleCollision(p.shipPolygon(), b.X, b.Y, bulletRadius)
+}
+
+func shipTip(p *Player) (float64, float64) {
+	cx := float64(p.Location.X)
+	cy := float64(p.Location.Y)
+	shipLength := 40.0
+	return cx + shipLength*math.Sin(p.ShipAngle), cy - shipLength*math.Cos(p.ShipAngle)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"image/color"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"golang.org/x/image/font/basicfont"
+)
+
+// popupFrames is how long a score popup floats up for.
+const popupFrames = 45
+
+type scorePopup struct {
+	X, Y   float64
+	Text   string
+	Colour color.RGBA
+	Life   int
+}
+
+// ScorePopups are the points that float up from wherever they were earned,
+// and what a mystery powerup turned out to be.
+// Like particles they're only for show and aren't part of the simulation.
+type ScorePopups struct {
+	popups []scorePopup
+}
+
+func (sp *ScorePopups) Update() {
+	alive := sp.popups[:0]
+	for _, p := range sp.popups {
+		p.Life--
+		p.Y -= 0.8
+		if p.Life > 0 {
+			alive = append(alive, p)
+		}
+	}
+	sp.popups = alive
+}
+
+func (sp *ScorePopups) Clear() {
+	sp.popups = sp.popups[:0]
+}
+
+func (sp *ScorePopups) Draw(screen *ebiten.Image) {
+	for _, p := range sp.popups {
+		bounds := text.BoundString(basicfont.Face7x13, p.Text)
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(p.X-float64(bounds.Dx())/2, p.Y)
+		op.ColorScale.ScaleWithColor(p.Colour)
+		op.ColorScale.ScaleAlpha(min(1, float32(p.Life)/15)) // fade out over the last quarter second
+		text.DrawWithOptions(screen, p.Text, basicfont.Face7x13, op)
+	}
+}
+
+func (g *Game) subscribePopups() {
+	// say what came out of a mystery powerup, the bad ones especially
+	g.events.effectStarted.subscribe(func(e EffectStarted) {
+		if e.From == nil || e.From.Type != powerUpMystery {
+			return
+		}
+		def := effectDefs[e.Kind]
+		colour := color.RGBA{255, 255, 255, 255}
+		if def.Debuff {
+			colour = g.settings.Accessibility.palette().Warning
+		}
+		msg := strings.ToUpper(def.Name) + "!"
+		g.popups.popups = append(g.popups.popups, scorePopup{X: e.From.X, Y: e.From.Y, Text: msg, Colour: colour, Life: 2 * popupFrames})
+	})
+
+	g.events.scoreChanged.subscribe(func(e ScoreChanged) {
+		msg := "+" + strconv.Itoa(e.Points)
+		if e.Multiplier > 1 {
+			msg += " x" + strconv.Itoa(e.Multiplier)
+		}
+		colour := color.RGBA{255, 255, 255, 255}
+		if len(g.players) > 1 {
+			colour = g.settings.Accessibility.palette().shipColour(e.Player.ID)
+		}
+		if e.Bonus != "" {
+			msg += " " + e.Bonus
+			colour = color.RGBA{255, 200, 0, 255}
+		}
+		g.popups.popups = append(g.popups.popups, scorePopup{X: e.X, Y: e.Y, Text: msg, Colour: colour, Life: popupFrames})
+	})
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+const (
+	powerupLifetime    = 600 // frames a dropped powerup stays around for, 10 seconds
+	powerupBlinkFrames = 180 // it blinks for the last 3 of them
+	powerupDriftSpeed  = 0.3 // pixels a frame
+
+	pickupRadius       = 32.0 // how close a ship has to get to collect one
+	magnetPickupRadius = 60.0 // the same with the magnet
+	magnetRadius       = 90.0 // how close a powerup has to be to start drifting to a ship
+	magnetBoostRadius  = 300.0
+	magnetPull         = 3.0 // pixels a frame at the ship, less further away
+
+	dropChance    = 0.10 // of an enemy dropping anything when it's destroyed
+	dropPityKills = 25   // enemies in a row without a drop before the next one is guaranteed
+
+	baseBombCapacity = 2
+	maxBombCapacity  = 5
+	maxLives         = 3
+)
+
+// powerupDef is everything about one type of powerup. collect is what it
+// does when a ship picks it up.
+type powerupDef struct {
+	Name    string          // in the stats
+	Label   string          // drawn under it when shape cues are on
+	Icon    image.Rectangle // in resources.TilesImage
+	Colour  color.RGBA      // the sparkle when it's picked up
+	collect func(g *Game, p *Player, pu *Powerup)
+}
+
+var powerupDefs = [numPowerupTypes]powerupDef{
+	powerupShield: {
+		Name:   "shield",
+		Label:  "S",
+		Icon:   image.Rect(0, 0, 32, 32),
+		Colour: color.RGBA{0, 255, 255, 255},
+		collect: func(g *Game, p *Player, pu *Powerup) {
+			g.startEffect(p, effectShield, pu)
+		},
+	},
+	powerupBomb: {
+		Name:    "bomb",
+		Label:   "B",
+		Icon:    image.Rect(32, 0, 64, 32),
+		Colour:  color.RGBA{255, 200, 0, 255},
+		collect: func(g *Game, p *Player, pu *Powerup) { p.addBomb() },
+	},
+	powerupInvincibleBullets: {
+		Name:   "invincible bullets",
+		Label:  "I",
+		Icon:   image.Rect(64, 0, 96, 32),
+		Colour: color.RGBA{255, 0, 255, 255},
+		collect: func(g *Game, p *Player, pu *Powerup) {
+			g.startEffect(p, effectInvincibleBullets, pu)
+		},
+	},
+	powerupFreezeEnemies: {
+		Name:   "freeze",
+		Label:  "F",
+		Icon:   image.Rect(0, 32, 32, 64),
+		Colour: color.RGBA{120, 180, 255, 255},
+		collect: func(g *Game, p *Player, pu *Powerup) {
+			g.startEffect(p, effectFreezeEnemies, pu)
+		},
+	},
+	powerUpMystery: {
+		Name:   "mystery",
+		Label:  "?",
+		Icon:   image.Rect(32, 32, 64, 64),
+		Colour: color.RGBA{255, 255, 255, 255},
+		collect: func(g *Game, p *Player, pu *Powerup) {
+			// Randomly choose a powerup type
+			r := g.rngs[rngMisc].Float64()
+			if r < 0.25 {
+				g.startEffect(p, effectShield, pu)
+			} else if r < 0.5 {
+				p.addBomb()
+			} else if r < 0.60 {
+				g.startEffect(p, effectInvincibleBullets, pu)
+			} else if r < 0.75 {
+				g.startEffect(p, effectFreezeEnemies, pu)
+			} else {
+				g.startEffect(p, effectInvincibleEnemies, pu)
+			}
+		},
+	},
+	powerupMagnet: {
+		Name:   "magnet",
+		Label:  "M",
+		Icon:   image.Rect(128, 0, 160, 32),
+		Colour: color.RGBA{0, 120, 255, 255},
+		collect: func(g *Game, p *Player, pu *Powerup) {
+			g.startEffect(p, effectMagnet, pu)
+		},
+	},
+	powerupTimeSlow: {
+		Name:   "time slow",
+		Label:  "T",
+		Icon:   image.Rect(96, 32, 128, 64),
+		Colour: color.RGBA{255, 190, 40, 255},
+		collect: func(g *Game, p *Player, pu *Powerup) {
+			g.startEffect(p, effectTimeSlow, pu)
+		},
+	},
+	powerupDrone: {
+		Name:   "drone",
+		Label:  "D",
+		Icon:   image.Rect(128, 32, 160, 64),
+		Colour: color.RGBA{0, 230, 255, 255},
+		collect: func(g *Game, p *Player, pu *Powerup) {
+			g.startEffect(p, effectDrone, pu)
+		},
+	},
+	powerupBombCapacity: {
+		Name:   "bomb capacity",
+		Label:  "+",
+		Icon:   image.Rect(160, 0, 192, 32),
+		Colour: color.RGBA{255, 200, 0, 255},
+		collect: func(g *Game, p *Player, pu *Powerup) {
+			p.BombCapacity = min(p.BombCapacity+1, maxBombCapacity)
+			p.addBomb()
+		},
+	},
+	powerupRepair: {
+		Name:   "repair",
+		Label:  "R",
+		Icon:   image.Rect(160, 32, 192, 64),
+		Colour: color.RGBA{40, 190, 60, 255},
+		collect: func(g *Game, p *Player, pu *Powerup) {
+			p.Lives = min(p.Lives+1, maxLives)
+		},
+	},
+}
+
+// powerupInfo is the def for a type, with a placeholder for anything unknown.
+func powerupInfo(powerupType int) powerupDef {
+	if powerupType > 0 && powerupType < numPowerupTypes && powerupDefs[powerupType].collect != nil {
+		return powerupDefs[powerupType]
+	}
+	return powerupDef{Name: "unknown", Label: "?", Colour: color.RGBA{255, 255, 255, 255}}
+}
+
+// dropWaves are how likely each powerup is to be the one an enemy drops.
+// The wave goes up at every anomaly milestone and the last row it has got
+// to is used, so the newer powerups turn up once the run gets going.
+var dropWaves = []struct {
+	FromWave int
+	Weights  [numPowerupTypes]int // by powerup type
+}{
+	{FromWave: 1, Weights: [numPowerupTypes]int{
+		powerupShield: 5, powerupBomb: 5, powerupFreezeEnemies: 5, powerupInvincibleBullets: 15,
+		powerupMagnet: 10, powerUpMystery: 60,
+	}},
+	{FromWave: 2, Weights: [numPowerupTypes]int{
+		powerupShield: 5, powerupBomb: 5, powerupFreezeEnemies: 5, powerupInvincibleBullets: 15,
+		powerupMagnet: 10, powerupTimeSlow: 10, powerupRepair: 3, powerUpMystery: 47,
+	}},
+	{FromWave: 3, Weights: [numPowerupTypes]int{
+		powerupShield: 5, powerupBomb: 5, powerupFreezeEnemies: 5, powerupInvincibleBullets: 15,
+		powerupMagnet: 10, powerupTimeSlow: 10, powerupRepair: 3, powerupDrone: 8, powerupBombCapacity: 4,
+		powerUpMystery: 35,
+	}},
+}
+
+// dropOverride changes the drop table for one size of enemy, anything left
+// unset is the wave's.
+type dropOverride struct {
+	Chance  float64
+	Weights map[int]int // replaces the wave's weight for these powerup types
+}
+
+// dropOverrides are by enemySize. The large ones take the most shooting so
+// they drop more often and more of the useful stuff, the small ones are
+// everywhere so they never drop a mystery.
+var dropOverrides = map[string]dropOverride{
+	"large": {Chance: 0.2, Weights: map[int]int{powerupShield: 15, powerupBomb: 10}},
+	"small": {Weights: map[int]int{powerUpMystery: 0}},
+}
+
+// wave is how far through the run it is, one more for every 1000 points.
+func (g *Game) wave() int {
+	return g.totalScore()/1000 + 1
+}
+
+// dropWeights is the drop table for e at the current wave.
+func (g *Game) dropWeights(e *Enemy) [numPowerupTypes]int {
+	weights := dropWaves[0].Weights
+	for _, w := range dropWaves {
+		if g.wave() >= w.FromWave {
+			weights = w.Weights
+		}
+	}
+	for powerupType, w := range dropOverrides[enemySize(e.Radius)].Weights {
+		weights[powerupType] = w
+	}
+	return weights
+}
+
+func dropChanceFor(e *Enemy) float64 {
+	if chance := dropOverrides[enemySize(e.Radius)].Chance; chance > 0 {
+		return chance
+	}
+	return dropChance
+}
+
+// rollDrop decides whether e drops anything as it goes, and what. It always
+// rolls, even when the drought forces a drop, so the rngDrops stream doesn't
+// depend on it.
+func (g *Game) rollDrop(e *Enemy) (int, bool) {
+	g.dropDrought++
+	if g.rngs[rngDrops].Float64() >= dropChanceFor(e) && g.dropDrought < dropPityKills {
+		return 0, false
+	}
+	g.dropDrought = 0
+	return g.pickDrop(e), true
+}
+
+// pickDrop chooses which powerup e drops using its drop table.
+func (g *Game) pickDrop(e *Enemy) int {
+	weights := g.dropWeights(e)
+	total := 0
+	for _, w := range weights {
+		total += w
+	}
+	if total == 0 {
+		return powerUpMystery
+	}
+	r := g.rngs[rngDrops].IntN(total)
+	for powerupType, w := range weights {
+		if r < w {
+			return powerupType
+		}
+		r -= w
+	}
+	return powerUpMystery
+}
+
+func (p *Player) addBomb() {
+	if p.Bombs < p.BombCapacity {
+		p.Bombs++
+	}
+}
+
+// dropPowerup leaves a powerup where an enemy was, drifting off in a random direction.
+func (g *Game) dropPowerup(x, y float64, powerupType int) {
+	angle := g.rngs[rngDrops].Float64() * 2 * math.Pi
+	g.powerups = append(g.powerups, &Powerup{
+		X:      x,
+		Y:      y,
+		VX:     math.Cos(angle) * powerupDriftSpeed,
+		VY:     math.Sin(angle) * powerupDriftSpeed,
+		Type:   powerupType,
+		Active: true,
+		Life:   powerupLifetime,
+	})
+}
+
+func (p *Player) pickupRadius() float64 {
+	if p.Effects.has(effectMagnet) {
+		return magnetPickupRadius
+	}
+	return pickupRadius
+}
+
+func (p *Player) magnetRadius() float64 {
+	if p.Effects.has(effectMagnet) {
+		return magnetBoostRadius
+	}
+	return magnetRadius
+}
+
+// updatePowerups ages, drifts and pulls in the powerups, and drops the ones
+// that have been collected or run out.
+func (g *Game) updatePowerups() {
+	screenWidth, screenHeight := g.Layout(0, 0)
+	kept := g.powerups[:0]
+	for _, pu := range g.powerups {
+		if !pu.Active {
+			continue
+		}
+		pu.Life--
+		if pu.Life <= 0 {
+			continue
+		}
+
+		// drift, bouncing off the edges so they stay where they can be reached.
+		// one dropped past an edge is put back inside rather than bouncing on the spot
+		pu.X += pu.VX
+		pu.Y += pu.VY
+		pu.X, pu.VX = bounce(pu.X, pu.VX, 16, float64(screenWidth)-16)
+		pu.Y, pu.VY = bounce(pu.Y, pu.VY, 16, float64(screenHeight)-16)
+
+		// the nearest ship in range pulls it in, harder the closer it gets
+		var nearest *Player
+		nearestDist := math.Inf(1)
+		for _, p := range g.players {
+			if !p.Alive {
+				continue
+			}
+			d := math.Hypot(float64(p.Location.X)-pu.X, float64(p.Location.Y)-pu.Y)
+			if d < p.magnetRadius() && d < nearestDist {
+				nearest, nearestDist = p, d
+			}
+		}
+		if nearest != nil && nearestDist > 1 {
+			pull := magnetPull * (1 - nearestDist/nearest.magnetRadius())
+			pu.X += (float64(nearest.Location.X) - pu.X) / nearestDist * pull
+			pu.Y += (float64(nearest.Location.Y) - pu.Y) / nearestDist * pull
+		}
+
+		kept = append(kept, pu)
+	}
+	g.powerups = kept
+}
+
+// bounce keeps pos between lo and hi, turning v round if it's heading out.
+func bounce(pos, v, lo, hi float64) (float64, float64) {
+	if pos < lo {
+		return lo, math.Abs(v)
+	}
+	if pos > hi {
+		return hi, -math.Abs(v)
+	}
+	return pos, v
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func newDropTestGame(seed uint64) *Game {
+	g := &Game{headless: true, settings: defaultSettings()}
+	cfg := g.newRunConfig(modeSurvival, 1)
+	cfg.Seed1, cfg.Seed2 = seed, seed+1
+	g.newRun(cfg)
+	return g
+}
+
+// testEnemies are one of each size, by the radius enemySize goes on.
+var testEnemies = map[string]*Enemy{
+	"small":  {Active: true, Radius: 10},
+	"medium": {Active: true, Radius: 30},
+	"large":  {Active: true, Radius: 50},
+}
+
+func TestPickDropFollowsTheDropTables(t *testing.T) {
+	const draws = 200000
+	const tolerance = 0.01 // of the total, a few times the spread at this many draws
+
+	for _, wave := range dropWaves {
+		for size, e := range testEnemies {
+			g := newDropTestGame(uint64(wave.FromWave))
+			g.players[0].Score = (wave.FromWave - 1) * 1000
+			if g.wave() != wave.FromWave {
+				t.Fatalf("wave %d: the game thinks it's wave %d", wave.FromWave, g.wave())
+			}
+
+			want := wave.Weights
+			for powerupType, w := range dropOverrides[size].Weights {
+				want[powerupType] = w
+			}
+			total := 0
+			for _, w := range want {
+				total += w
+			}
+
+			var counts [numPowerupTypes]int
+			for i := 0; i < draws; i++ {
+				counts[g.pickDrop(e)]++
+			}
+			for powerupType, w := range want {
+				expected := float64(w) / float64(total)
+				got := float64(counts[powerupType]) / draws
+				if w == 0 && counts[powerupType] > 0 {
+					t.Errorf("wave %d %s: dropped %s %d times, its weight is 0",
+						wave.FromWave, size, powerupInfo(powerupType).Name, counts[powerupType])
+				}
+				if math.Abs(got-expected) > tolerance {
+					t.Errorf("wave %d %s: %s dropped %.3f of the time, want %.3f",
+						wave.FromWave, size, powerupInfo(powerupType).Name, got, expected)
+				}
+			}
+		}
+	}
+}
+
+func TestRollDropChance(t *testing.T) {
+	const kills = 200000
+
+	for size, e := range testEnemies {
+		g := newDropTestGame(7)
+		drops := 0
+		for i := 0; i < kills; i++ {
+			if _, ok := g.rollDrop(e); ok {
+				drops++
+			}
+		}
+		// the pity timer only ever adds drops, and hardly any at these rates
+		got, want := float64(drops)/kills, dropChanceFor(e)
+		if got < want-0.005 || got > want+0.02 {
+			t.Errorf("%s: dropped %.3f of the time, want about %.3f", size, got, want)
+		}
+	}
+}
+
+func TestRollDropPityTimer(t *testing.T) {
+	e := testEnemies["medium"]
+
+	// however the last roll goes, the 25th kill in a row without one drops something
+	for seed := uint64(0); seed < 500; seed++ {
+		g := newDropTestGame(seed)
+		g.dropDrought = dropPityKills - 1
+		if _, ok := g.rollDrop(e); !ok {
+			t.Fatalf("seed %d: nothing dropped after %d kills without one", seed, dropPityKills)
+		}
+		if g.dropDrought != 0 {
+			t.Fatalf("seed %d: the drought is %d after a drop", seed, g.dropDrought)
+		}
+	}
+
+	// and in a long run of kills that's the longest anyone goes without
+	g := newDropTestGame(1)
+	drought, longest := 0, 0
+	for i := 0; i < 200000; i++ {
+		if _, ok := g.rollDrop(e); ok {
+			drought = 0
+			continue
+		}
+		drought++
+		longest = max(longest, drought)
+	}
+	if longest != dropPityKills-1 {
+		t.Fatalf("longest drought was %d kills, want %d", longest, dropPityKills-1)
+	}
+}
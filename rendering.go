@@ -1,7 +1,6 @@
 package main
 
 import (
-	"image"
 	"image/color"
 	"math"
 	"strconv"
@@ -16,111 +15,187 @@ import (
 
 var whiteImg *ebiten.Image
 
+const (
+	anomalyPeakAlpha      = 150 // the most Anomaly.Alpha gets to, where the overlay is at its strongest
+	anomalyReducedAlpha   = 120 // how far it dips when flashing is reduced
+	anomalyOverlayOpacity = 106 // out of 255 at the peak, enough to see what's going on underneath
+)
+
 func FillScreen(screen *ebiten.Image) {
 	screen.Fill(color.RGBA{0, 0, 0, 255}) // Fill the screen with black
 }
 
-func DrawShip(g *Game, screen *ebiten.Image, isBlack bool) {
+func DrawShip(g *Game, p *Player, screen *ebiten.Image, isBlack bool) {
 	// player is an elongated diamond shape
-	cx := float64(g.playerLocation.X)
-	cy := float64(g.playerLocation.Y)
-	shipHeight := float64(75.0)
-	shipWidth := float64(30.0)
-
-	// Define the four points of the diamond
-	topX, topY := cx, cy-shipHeight/2
-	rightX, rightY := cx+shipWidth/2, cy
-	bottomX, bottomY := cx, cy+shipHeight/4
-	leftX, leftY := cx-shipWidth/2, cy
+	cx := float64(p.Location.X)
+	cy := float64(p.Location.Y)
+	poly := p.shipPolygon()
+	topX, topY := poly[0][0], poly[0][1]
+	rightX, rightY := poly[1][0], poly[1][1]
+	bottomX, bottomY := poly[2][0], poly[2][1]
+	leftX, leftY := poly[3][0], poly[3][1]
 
-	angle := g.shipAngle
-	topX, topY = RotatePoint(topX, topY, cx, cy, angle)
-	rightX, rightY = RotatePoint(rightX, rightY, cx, cy, angle)
-	bottomX, bottomY = RotatePoint(bottomX, bottomY, cx, cy, angle)
-	leftX, leftY = RotatePoint(leftX, leftY, cx, cy, angle)
-
-	shipColour := color.RGBA{255, 255, 255, 255}
+	palette := g.settings.Accessibility.palette()
+	shipColour := palette.shipColour(p.ID)
 
 	if isBlack {
 		shipColour = color.RGBA{0, 0, 0, 255} // black
 	}
 
-	if g.hasShield {
+	if shieldTimer := p.Effects.frames(effectShield); shieldTimer > 0 {
+		shipColour = palette.Shield
+
 		// Flash for last 2 seconds (120 frames)
-		if g.shieldTimer <= 120 {
-			// Alternate every 10 frames between white and cyan
-			if (g.shieldTimer/10)%2 == 0 {
-				shipColour = color.RGBA{0, 255, 255, 220} // cyan
-			} else {
-				shipColour = color.RGBA{255, 255, 255, 255} // white
-			}
-		} else {
-			shipColour = color.RGBA{0, 255, 255, 180} // normal cyan
+		if shieldTimer <= 120 && !g.settings.Accessibility.blink(shieldTimer, 10) {
+			shipColour = palette.ShieldBlink
+		}
+
+		// a bubble around the ship so the shield doesn't rely on colour,
+		// it shrinks as the shield runs out instead of flashing
+		if g.settings.Accessibility.ShapeCues {
+			bubble := float32(45 * math.Min(1, float64(shieldTimer)/120+0.5))
+			vector.StrokeCircle(screen, float32(cx), float32(cy), bubble, 1, palette.Shield, true)
 		}
 	}
 
-	vector.StrokeLine(screen, float32(topX), float32(topY), float32(rightX), float32(rightY), 2, shipColour, true)
-	vector.StrokeLine(screen, float32(rightX), float32(rightY), float32(bottomX), float32(bottomY), 2, shipColour, true)
-	vector.StrokeLine(screen, float32(bottomX), float32(bottomY), float32(leftX), float32(leftY), 2, shipColour, true)
-	vector.StrokeLine(screen, float32(leftX), float32(leftY), float32(topX), float32(topY), 2, shipColour, true)
+	// blink while it can't be hurt after losing a life
+	if p.Invulnerable > 0 && !isBlack && !g.settings.Accessibility.blink(p.Invulnerable, 8) {
+		shipColour = color.RGBA{shipColour.R / 3, shipColour.G / 3, shipColour.B / 3, 255}
+	}
+
+	width := palette.StrokeWidth
+	vector.StrokeLine(screen, float32(topX), float32(topY), float32(rightX), float32(rightY), width, shipColour, true)
+	vector.StrokeLine(screen, float32(rightX), float32(rightY), float32(bottomX), float32(bottomY), width, shipColour, true)
+	vector.StrokeLine(screen, float32(bottomX), float32(bottomY), float32(leftX), float32(leftY), width, shipColour, true)
+	vector.StrokeLine(screen, float32(leftX), float32(leftY), float32(topX), float32(topY), width, shipColour, true)
+	drawDrones(screen, p, shipColour, width)
+
+	// in co-op the ships are numbered as well as coloured
+	if len(g.players) > 1 && g.settings.Accessibility.ShapeCues && !isBlack {
+		text.Draw(screen, strconv.Itoa(p.ID+1), basicfont.Face7x13, int(cx)-3, int(cy)+5, shipColour)
+	}
 }
 
 func DrawEnemies(g *Game, screen *ebiten.Image) {
+	palette := g.settings.Accessibility.palette()
+	width := palette.StrokeWidth
+
 	for _, e := range g.enemies {
-		col := color.RGBA{255, 0, 0, 255}
+		col := palette.EnemyHit
 
 		// flash them red if they are hit
 		if e.HitTimer == 0 {
-			bu
-			col = color.RGBA{255, 255, 0, 255}
+			col = palette.Enemy
 		}
 
-		if e.IsInvincible || g.invincibleEnemiesTimer > 0 {
-			vector.DrawFilledCircle(screen, float32(e.X), float32(e.Y), float32(e.Radius), color.RGBA{255, 0, 0, 255}, false)
-			vector.StrokeCircle(screen, float32(e.X), float32(e.Y), float32(e.Radius), 2, col, false)
+		x, y, r := float32(e.X), float32(e.Y), float32(e.Radius)
+		if e.IsInvincible || g.enemiesInvincible() {
+			vector.DrawFilledCircle(screen, x, y, r, palette.Invincible, false)
+			vector.StrokeCircle(screen, x, y, r, width, col, false)
+
+			// an inner ring and a cross so invincible enemies read as "armoured" without colour
+			if g.settings.Accessibility.ShapeCues {
+				vector.StrokeCircle(screen, x, y, r*0.6, width, col, false)
+				d := r * 0.42
+				vector.StrokeLine(screen, x-d, y-d, x+d, y+d, width, col, false)
+				vector.StrokeLine(screen, x-d, y+d, x+d, y-d, width, col, false)
+			}
 		} else {
-			vector.StrokeCircle(screen, float32(e.X), float32(e.Y), float32(e.Radius), 2, col, false)
+			vector.StrokeCircle(screen, x, y, r, width, col, false)
 		}
 	}
 }
 
 func DrawBullets(g *Game, screen *ebiten.Image) {
+	palette := g.settings.Accessibility.palette()
 
-	bulletColor := color.RGBA{0, 255, 0, 100}
-
-	if g.invincibleBulletsTimer > 0 {
-		bulletColor = color.RGBA{255, 0, 255, 100}
-	}
 	for _, b := range g.bullets {
 		if b.Active {
+			// bullets are powered up by whoever fired them
+			powered := b.Owner < len(g.players) && g.players[b.Owner].Effects.has(effectInvincibleBullets)
+			bulletColor := palette.Bullet
+			if powered {
+				bulletColor = palette.PowerBullet
+			}
 			vector.DrawFilledCircle(screen, float32(b.X), float32(b.Y), 4, bulletColor, false)
+
+			// invincible bullets get a ring round them as well as the colour change
+			if powered && g.settings.Accessibility.ShapeCues {
+				vector.StrokeCircle(screen, float32(b.X), float32(b.Y), 7, 1, bulletColor, false)
+			}
 		}
 	}
 }
 
-// draws the score in the top left corner
+// draws the score in the top left corner, player two's goes in the top right
 func DrawScore(g *Game, screen *ebiten.Image) {
-	// Draw the score at the top left corner
-	scoreText := "Score: " + strconv.Itoa(g.score)
-	text.Draw(screen, scoreText, basicfont.Face7x13, 10, 20, color.White)
+	palette := g.settings.Accessibility.palette()
+	for _, p := range g.players {
+		scoreText := "Score: " + strconv.Itoa(p.Score)
+		bombText := "Bombs: " + strconv.Itoa(p.Bombs)
+		if g.difficulty.tuning().Lives > 0 || p.Lives > 0 {
+			bombText += "  Lives: " + strconv.Itoa(p.Lives)
+		}
+		col := color.Color(color.White)
+		if len(g.players) > 1 {
+			scoreText = "P" + strconv.Itoa(p.ID+1) + " " + scoreText
+			col = palette.shipColour(p.ID)
+			if !p.Alive {
+				scoreText += " (dead)"
+			}
+		}
+
+		x := 10
+		if p.ID == 1 {
+			w := max(text.BoundString(basicfont.Face7x13, scoreText).Dx(), text.BoundString(basicfont.Face7x13, bombText).Dx())
+			x = screen.Bounds().Dx() - w - 10
+		}
+		text.Draw(screen, scoreText, basicfont.Face7x13, x, 20, col)
 
-	// Draw the bomb count below the score
-	bombText := "Bombs: " + strconv.Itoa(g.bombs)
-	text.Draw(screen, bombText, basicfont.Face7x13, 10, 40, color.RGBA{255, 200, 0, 255})
+		// Draw the bomb count below the score
+		text.Draw(screen, bombText, basicfont.Face7x13, x, 40, color.RGBA{255, 200, 0, 255})
+
+		// and the combo while there is one, with a bar for how long is left to keep it going
+		if p.Combo > 0 {
+			comboText := "x" + strconv.Itoa(p.multiplier()) + "  " + strconv.Itoa(p.Combo) + " chain"
+			text.Draw(screen, comboText, basicfont.Face7x13, x, 60, col)
+			bar := float32(p.ComboTimer) / comboWindow * 80
+			vector.DrawFilledRect(screen, float32(x), 66, bar, 3, col, false)
+		}
+
+		// the ship's powerups under that, player two's lined up on the right
+		effectsX := 10
+		if p.ID == 1 {
+			effectsX = screen.Bounds().Dx() - effectsWidth(p.Effects) - 10
+		}
+		drawEffects(screen, p.Effects, effectsX, 76, palette)
+	}
+
+	// and the ones on the enemies in the middle at the top
+	drawEffects(screen, g.enemyEffects, (screen.Bounds().Dx()-effectsWidth(g.enemyEffects))/2, 10, palette)
 }
 
 func DrawSplashScreen(g *Game, screen *ebiten.Image) {
 	bounds := screen.Bounds()
 	w, h := bounds.Dx(), bounds.Dy()
-	msg := "SPACE SHOOTER\n\nControls:\n\nA/D - Rotate\nW/S - Accelerate/Decelerate\nSPACE - Shoot\nB - Bomb\n\nPress ENTER to start"
+	msg := "SPACE SHOOTER\n\nControls:\n\n" + controlsHelp(g.settings.PlayerKeys)
 	lines := strings.Split(msg, "\n")
 	y := 0
 
-	if g.previousScore > 0 {
-		msg = "GAME OVER\n\nPress ENTER to start again"
+	if g.lastMatch != nil {
+		lines = versusSummary(g.lastMatch)
+	} else if len(g.previousScores) > 0 {
+		msg = "GAME OVER"
 		lines = strings.Split(msg, "\n")
+		if len(g.stats.Players) > 0 {
+			lines = append(lines, "")
+			lines = append(lines, g.statsSummary()...)
+		}
 
-		scoreText := "Score: " + strconv.Itoa(g.previousScore)
+		scoreText := "Score: " + strconv.Itoa(g.previousScores[0])
+		if len(g.previousScores) > 1 {
+			scoreText = "P1: " + strconv.Itoa(g.previousScores[0]) + "   P2: " + strconv.Itoa(g.previousScores[1])
+		}
 
 		scale := 3.0 // 3x bigger
 		face := basicfont.Face7x13
@@ -137,11 +212,39 @@ func DrawSplashScreen(g *Game, screen *ebiten.Image) {
 		text.DrawWithOptions(screen, scoreText, face, op)
 	}
 	y = h/2 - len(lines)*12
+	if g.lastMatch == nil && len(g.previousScores) > 0 {
+		y = h/2 - 12 // under the big score, however many stats there are
+	}
 	for i, line := range lines {
 		bounds := text.BoundString(basicfont.Face7x13, line)
 		x := (w - bounds.Dx()) / 2
 		text.Draw(screen, line, basicfont.Face7x13, x, y+20*i, color.White)
 	}
+
+	menuY := y + 20*len(lines) + 40
+	if g.online != nil && g.lastMatch == nil && len(g.previousScores) == 1 {
+		if rank := g.online.rankText(); rank != "" {
+			bounds := text.BoundString(basicfont.Face7x13, rank)
+			text.Draw(screen, rank, basicfont.Face7x13, (w-bounds.Dx())/2, menuY, color.RGBA{255, 200, 0, 255})
+			menuY += 40
+		}
+	}
+	if g.netStatus != "" {
+		bounds := text.BoundString(basicfont.Face7x13, g.netStatus)
+		text.Draw(screen, g.netStatus, basicfont.Face7x13, (w-bounds.Dx())/2, menuY, color.RGBA{255, 200, 0, 255})
+		menuY += 40
+	}
+
+	if m := g.topMenu(); m != nil && m.isTitle {
+		g.drawMenu(screen, m, menuY)
+	}
+}
+
+// DrawFPS puts the current frame rate in the bottom right corner, out of the way of player two's score.
+func DrawFPS(screen *ebiten.Image) {
+	msg := "FPS: " + strconv.Itoa(int(ebiten.ActualFPS()+0.5))
+	bounds := text.BoundString(basicfont.Face7x13, msg)
+	text.Draw(screen, msg, basicfont.Face7x13, screen.Bounds().Dx()-bounds.Dx()-10, screen.Bounds().Dy()-10, color.White)
 }
 
 func DrawPowerups(g *Game, screen *ebiten.Image) {
@@ -150,76 +253,83 @@ func DrawPowerups(g *Game, screen *ebiten.Image) {
 			continue
 		}
 
-		if p.Type == powerupShield {
-
-			shieldRect := image.Rect(0, 0, 32, 32) // x0, y0, x1, y1 in pixels
-			shieldSprite := resources.TilesImage.SubImage(shieldRect).(*ebiten.Image)
-
-			op := &ebiten.DrawImageOptions{}
-			op.GeoM.Translate(p.X-16, p.Y-16) // Center the sprite
-			screen.DrawImage(shieldSprite, op)
+		// blink for the last few seconds before it goes, or fade out if flashing is turned off
+		alpha := float32(1)
+		if p.Life < powerupBlinkFrames {
+			if g.settings.Accessibility.Flashing == flashingOff {
+				alpha = float32(p.Life) / powerupBlinkFrames
+			} else if !g.settings.Accessibility.blink(p.Life, 8) {
+				continue
+			}
 		}
 
-		if p.Type == powerupBomb {
-
-			bombRect := image.Rect(32, 0, 64, 32) // x0, y0, x1, y1 in pixels
-			bombSprite := resources.TilesImage.SubImage(bombRect).(*ebiten.Image)
-
-			op := &ebiten.DrawImageOptions{}
-			op.GeoM.Translate(p.X-16, p.Y-16) // Center the sprite
-			screen.DrawImage(bombSprite, op)
+		sprite := resources.TilesImage.SubImage(powerupInfo(p.Type).Icon).(*ebiten.Image)
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(p.X-16, p.Y-16) // Center the sprite
+		op.ColorScale.ScaleAlpha(alpha)
+		screen.DrawImage(sprite, op)
+
+		// a letter under each powerup so they can be told apart without relying on the sprite colours
+		if g.settings.Accessibility.ShapeCues {
+			label := powerupInfo(p.Type).Label
+			bounds := text.BoundString(basicfont.Face7x13, label)
+			text.Draw(screen, label, basicfont.Face7x13, int(p.X)-bounds.Dx()/2, int(p.Y)+30, color.White)
 		}
+	}
+}
 
-		if p.Type == powerupInvincibleBullets {
-			bulletRect := image.Rect(64, 0, 96, 32) // x0, y0, x1, y1 in pixels
-			bulletSprite := resources.TilesImage.SubImage(bulletRect).(*ebiten.Image)
+func (a *Anomaly) DrawAnomaly(screen *ebiten.Image, access Accessibility) {
+	if !a.IsActive || a.Incoming > 0 {
+		return
+	}
 
-			op := &ebiten.DrawImageOptions{}
-			op.GeoM.Translate(p.X-16, p.Y-16) // Center the sprite
-			screen.DrawImage(bulletSprite, op)
+	// the anomaly strobes between 150 and 10 while it's flashing. Turned
+	// down it's held steady, or reduced it only dips to 120 so the flashes
+	// are much softer
+	level := a.Alpha
+	if a.flashing {
+		switch access.Flashing {
+		case flashingOff:
+			level = anomalyPeakAlpha
+		case flashingReduced:
+			level = max(level, anomalyReducedAlpha)
 		}
+	}
 
-		if p.Type == powerupFreezeEnemies {
-			rect := image.Rect(0, 32, 32, 64) // x0, y0, x1, y1 in pixels
-			sprite := resources.TilesImage.SubImage(rect).(*ebiten.Image)
+	opacity := min(float64(level)/anomalyPeakAlpha, 1) * anomalyOverlayOpacity
+	palette := access.palette()
+	tint := palette.Anomaly
+	a.variant().draw(a, screen, color.RGBA{
+		uint8(float64(tint.R) * opacity / 255), // premultiplied
+		uint8(float64(tint.G) * opacity / 255),
+		uint8(float64(tint.B) * opacity / 255),
+		uint8(opacity),
+	}, palette)
+}
 
-			op := &ebiten.DrawImageOptions{}
-			op.GeoM.Translate(p.X-16, p.Y-16) // Center the sprite
-			screen.DrawImage(sprite, op)
-		}
+// drawOverlayWithHoles covers the screen in fill except for the holes, the
+// anomalies and the versus safe zone all use it.
+func drawOverlayWithHoles(screen *ebiten.Image, fill color.RGBA, holes ...SafeZone) {
+	w, h := screen.Bounds().Dx(), screen.Bounds().Dy()
 
-		if p.Type == powerUpMystery {
-			rect := image.Rect(32, 32, 64, 64) // x0, y0, x1, y1 in pixels
-			sprite := resources.TilesImage.SubImage(rect).(*ebiten.Image)
+	// 1. Draw the overlay to an offscreen image
+	overlay := ebiten.NewImage(w, h)
+	overlay.Fill(fill)
 
-			op := &ebiten.DrawImageOptions{}
-			op.GeoM.Translate(p.X-16, p.Y-16) // Center the sprite
-			screen.DrawImage(sprite, op)
+	// 2. Punch a transparent hole in the overlay for each safe zone
+	for _, z := range holes {
+		diameter := int(z.Radius * 2)
+		if diameter <= 0 {
+			continue
 		}
-	}
-}
+		mask := ebiten.NewImage(diameter, diameter)
+		drawFilledCircle(mask, z.Radius, z.Radius, z.Radius, color.White)
 
-func (a *Anomaly) DrawAnomaly(screen *ebiten.Image) {
-	if !a.IsActive || a.Incoming > 0 {
-		return
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(z.X-z.Radius, z.Y-z.Radius)
+		op.CompositeMode = ebiten.CompositeModeDestinationOut
+		overlay.DrawImage(mask, op)
 	}
-	w, h := screen.Size()
-
-	// 1. Draw red overlay to an offscreen image
-	overlay := ebiten.NewImage(w, h)
-	alpha := uint8(255 * a.Alpha)
-	r := 255 * alpha
-	overlay.Fill(color.RGBA{r, 0, 0, alpha})
-
-	// 2. Punch a transparent hole in the overlay for the safe zone
-	diameter := int(a.SafeRadius * 2)
-	mask := ebiten.NewImage(diameter, diameter)
-	drawFilledCircle(mask, a.SafeRadius, a.SafeRadius, a.SafeRadius, color.White)
-
-	op := &ebiten.DrawImageOptions{}
-	op.GeoM.Translate(a.SafeX-a.SafeRadius, a.SafeY-a.SafeRadius)
-	op.CompositeMode = ebiten.CompositeModeDestinationOut
-	overlay.DrawImage(mask, op)
 
 	// 3. Draw the overlay (with hole) onto the screen
 	screen.DrawImage(overlay, nil)
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+)
+
+// replayVersion is bumped whenever the simulation changes, an older replay
+// wouldn't play back the same.
+const replayVersion = 1
+
+// replayFile is the last local run, for `space-shooter verify` or sharing.
+const replayFile = "replay.json"
+
+// Replay is everything needed to play a run again exactly: how it started
+// and every frame's inputs. The simulation is deterministic, so that's enough.
+type Replay struct {
+	Version int       `json:"version"`
+	Config  RunConfig `json:"config"`
+	Inputs  []byte    `json:"inputs"` // Config.Players bytes per frame, from PlayerInput.bits
+}
+
+func newReplay(cfg RunConfig) *Replay {
+	return &Replay{Version: replayVersion, Config: cfg}
+}
+
+// record is called by step with each frame's inputs.
+func (r *Replay) record(inputs []PlayerInput) {
+	for _, in := range inputs {
+		r.Inputs = append(r.Inputs, in.bits())
+	}
+}
+
+func (r *Replay) Frames() int {
+	if r.Config.Players == 0 {
+		return 0
+	}
+	return len(r.Inputs) / r.Config.Players
+}
+
+// frame is the inputs for frame i.
+func (r *Replay) frame(i int) []PlayerInput {
+	inputs := make([]PlayerInput, r.Config.Players)
+	for p := range inputs {
+		inputs[p] = inputFromBits(r.Inputs[i*r.Config.Players+p])
+	}
+	return inputs
+}
+
+func (r *Replay) Encode() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// Hash identifies the replay, it's what a leaderboard submission is tied to.
+func (r *Replay) Hash() string {
+	data, err := r.Encode()
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// saveReplay keeps the run that just ended. Network games don't record one.
+func (g *Game) saveReplay() {
+	if g.replay == nil {
+		return
+	}
+	data, err := g.replay.Encode()
+	if err == nil {
+		err = writeStorage(replayFile, data)
+	}
+	if err != nil {
+		log.Printf("saving replay: %v", err)
+	}
+}
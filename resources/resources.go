@@ -25,4 +25,6 @@ var (
 	//go:embed starfield.png
 	BackgroundPNG   []byte
 	BackgroundImage *ebiten.Image
+	//go:embed sfx.json
+	SoundEffectsJSON []byte
 )
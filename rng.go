@@ -0,0 +1,37 @@
+package main
+
+import "math/rand/v2"
+
+// rngStream picks which of the run's random number generators to draw from.
+// Spawns, drops and the anomaly each get their own so what happens in one
+// doesn't shift the others, e.g. shooting more enemies doesn't change what
+// spawns next. Everyone on the same seed sees the same of each, which is
+// what makes the daily challenge fair.
+type rngStream int
+
+const (
+	rngMisc    rngStream = iota // splitting enemies, mystery powerups
+	rngSpawns                   // where enemies come from and which are invincible
+	rngDrops                    // powerups dropped by destroyed enemies
+	rngAnomaly                  // where the safe zone goes
+	numRNGStreams
+)
+
+// seedRun gives the run its random number generators, everything random in
+// the simulation draws from one of them. Each stream's seed is mixed from
+// the run's seed so the streams don't follow each other.
+func (g *Game) seedRun(seed1, seed2 uint64) {
+	for i := range g.rngs {
+		mix := splitmix64(uint64(i) + 1)
+		g.rngSources[i] = rand.NewPCG(splitmix64(seed1^mix), splitmix64(seed2+mix))
+		g.rngs[i] = rand.New(g.rngSources[i])
+	}
+}
+
+// splitmix64 scrambles x, so nearby seeds end up nowhere near each other.
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
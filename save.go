@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"math/rand/v2"
+)
+
+const saveFile = "save.json"
+
+// saveVersion is bumped whenever savedGame changes shape. Old saves are
+// refused rather than guessed at, losing a run is better than resuming a
+// broken one.
+const saveVersion = 1
+
+// savedGame is everything needed to carry on a run exactly where it left off.
+// Particles and screen effects are left out, they don't change what happens.
+type savedGame struct {
+	Version int `json:"version"`
+
+	Players        []*Player `json:"players"`
+	FriendlyFire   bool      `json:"friendlyFire"`
+	BombInvincible bombRule  `json:"bombInvincible"`
+	Mode           GameMode  `json:"mode"`
+	Versus         Versus    `json:"versus"`
+
+	Enemies  []*Enemy   `json:"enemies"`
+	Bullets  []*Bullet  `json:"bullets"`
+	Powerups []*Powerup `json:"powerups"`
+
+	FlashTimer   int           `json:"flashTimer"`
+	EnemyEffects ActiveEffects `json:"enemyEffects"`
+	DropDrought  int           `json:"dropDrought"`
+	Shockwaves   []*Shockwave  `json:"shockwaves"`
+
+	Anomaly    Anomaly    `json:"anomaly"`
+	Difficulty Difficulty `json:"difficulty"`
+	Daily      string     `json:"daily,omitempty"`
+	Practice   bool       `json:"practice,omitempty"`
+	RNG        [][]byte   `json:"rng"` // each stream's PCG state, encoded by its MarshalBinary
+
+	Replay *Replay `json:"replay,omitempty"` // so a continued run can still go on the leaderboard
+}
+
+func (g *Game) snapshot() (savedGame, error) {
+	rng := make([][]byte, len(g.rngSources))
+	for i, source := range g.rngSources {
+		state, err := source.MarshalBinary()
+		if err != nil {
+			return savedGame{}, err
+		}
+		rng[i] = state
+	}
+
+	return savedGame{
+		Version:        saveVersion,
+		Players:        g.players,
+		FriendlyFire:   g.friendlyFire,
+		BombInvincible: g.bombInvincible,
+		Shockwaves:     g.shockwaves,
+		Mode:           g.mode,
+		Versus:         g.versus,
+		Enemies:        g.enemies,
+		Bullets:        g.bullets,
+		Powerups:       g.powerups,
+		FlashTimer:     g.flashTimer,
+		EnemyEffects:   g.enemyEffects,
+		DropDrought:    g.dropDrought,
+		Anomaly:        g.Anomaly,
+		Difficulty:     g.difficulty,
+		Daily:          g.daily,
+		Practice:       g.practice,
+		RNG:            rng,
+		Replay:         g.replay,
+	}, nil
+}
+
+func (g *Game) restore(s savedGame) error {
+	if len(s.RNG) != int(numRNGStreams) {
+		return fmt.Errorf("save has %d random number generators, expected %d", len(s.RNG), numRNGStreams)
+	}
+	for i, state := range s.RNG {
+		source := &rand.PCG{}
+		if err := source.UnmarshalBinary(state); err != nil {
+			return fmt.Errorf("restoring random number generator: %w", err)
+		}
+		g.rngSources[i] = source
+		g.rngs[i] = rand.New(source)
+	}
+	g.players = s.Players
+	g.friendlyFire = s.FriendlyFire
+	g.bombInvincible = s.BombInvincible
+	g.shockwaves = s.Shockwaves
+	g.mode = s.Mode
+	g.versus = s.Versus
+	g.enemies = s.Enemies
+	g.bullets = s.Bullets
+	g.powerups = s.Powerups
+	g.flashTimer = s.FlashTimer
+	g.enemyEffects = s.EnemyEffects
+	g.dropDrought = s.DropDrought
+	g.Anomaly = s.Anomaly
+	g.difficulty = s.Difficulty
+	g.daily = s.Daily
+	g.practice = s.Practice
+	g.replay = s.Replay
+	return nil
+}
+
+func encodeSave(g *Game) ([]byte, error) {
+	s, err := g.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(s)
+}
+
+func decodeSave(data []byte) (savedGame, error) {
+	var s savedGame
+	if err := json.Unmarshal(data, &s); err != nil {
+		return savedGame{}, fmt.Errorf("parsing save: %w", err)
+	}
+	if s.Version != saveVersion {
+		return savedGame{}, fmt.Errorf("save is version %d, this build reads version %d", s.Version, saveVersion)
+	}
+	return s, nil
+}
+
+// autosave is called when the player quits or the window loses focus mid run.
+func (g *Game) autosave() {
+	if g.showSplash || g.rngs[rngMisc] == nil {
+		return // nothing in progress
+	}
+	if g.network != nil {
+		return // can't be continued without the other player
+	}
+	data, err := encodeSave(g)
+	if err != nil {
+		log.Printf("saving game: %v", err)
+		return
+	}
+	if err := writeStorage(saveFile, data); err != nil {
+		log.Printf("saving game: %v", err)
+	}
+	g.saveStats()
+}
+
+func hasAutosave() bool {
+	_, err := readStorage(saveFile)
+	return err == nil
+}
+
+// clearAutosave is called when a run ends, so a finished run can't be continued.
+func clearAutosave() {
+	for _, name := range []string{saveFile, saveStatsFile} {
+		if err := deleteStorage(name); err != nil {
+			log.Printf("removing save: %v", err)
+		}
+	}
+}
+
+// continueGame loads the autosave and picks the run back up.
+func (g *Game) continueGame() error {
+	data, err := readStorage(saveFile)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("there is no saved game")
+		}
+		return err
+	}
+	s, err := decodeSave(data)
+	if err != nil {
+		return err
+	}
+	if len(s.Players) == 0 || len(s.Players) > maxPlayers {
+		return fmt.Errorf("save has %d players", len(s.Players))
+	}
+
+	g.Reset()
+	g.particles.Clear()
+	g.popups.Clear()
+	g.effects.Reset()
+	g.lastMatch = nil
+	g.achievements.newRun(true)
+	g.loadStats(len(s.Players))
+	if err := g.restore(s); err != nil {
+		return err
+	}
+	g.menus = nil
+	g.showSplash = false
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"math/rand/v2"
+	"reflect"
+	"testing"
+)
+
+// scriptedInputs is a fixed run of button mashing, with fire held so
+// there's always something going on.
+func scriptedInputs(frames int) []PlayerInput {
+	r := rand.New(rand.NewPCG(3, 4))
+	inputs := make([]PlayerInput, frames)
+	for i := range inputs {
+		inputs[i] = inputFromBits(byte(r.IntN(64)) &^ (1 << 5))
+		inputs[i].Fire = true
+	}
+	return inputs
+}
+
+// resumeSave saves g and loads it into a new game, the way continueGame does.
+func resumeSave(t *testing.T, g *Game) *Game {
+	t.Helper()
+	data, err := encodeSave(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := decodeSave(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resumed := &Game{headless: true, settings: defaultSettings()}
+	resumed.Reset()
+	resumed.achievements.newRun(true)
+	resumed.stats = newRunStats(len(s.Players))
+	if err := resumed.restore(s); err != nil {
+		t.Fatal(err)
+	}
+	resumed.showSplash = false
+	return resumed
+}
+
+// checkResumesIdentically saves g, loads it into a new game and plays both
+// on with the same inputs, they have to stay the same every frame.
+func checkResumesIdentically(t *testing.T, g *Game, inputs []PlayerInput) {
+	t.Helper()
+	resumed := resumeSave(t, g)
+	if !reflect.DeepEqual(resumed.Anomaly, g.Anomaly) {
+		t.Fatalf("anomaly came back as %+v, saved %+v", resumed.Anomaly, g.Anomaly)
+	}
+	if got, want := resumed.checksum(), g.checksum(); got != want {
+		t.Fatalf("checksum %x straight after loading, saved %x", got, want)
+	}
+
+	for i, in := range inputs {
+		g.step([]PlayerInput{in})
+		resumed.step([]PlayerInput{in})
+		if got, want := resumed.checksum(), g.checksum(); got != want {
+			t.Fatalf("resumed game went its own way %d frames after loading", i+1)
+		}
+		if resumed.showSplash != g.showSplash {
+			t.Fatalf("only one of them ended %d frames after loading", i+1)
+		}
+		if g.showSplash {
+			return
+		}
+	}
+
+	// the checksum has every stream's state in it, this is just to be sure
+	for i := range g.rngs {
+		if got, want := resumed.rngs[i].Uint64(), g.rngs[i].Uint64(); got != want {
+			t.Fatalf("random number stream %d draws %x after resuming, %x without", i, got, want)
+		}
+	}
+}
+
+func TestSaveResumesIdentically(t *testing.T) {
+	const window = 60
+	inputs := scriptedInputs(2000)
+
+	g := &Game{headless: true, settings: defaultSettings()}
+	cfg := g.newRunConfig(modeSurvival, 1)
+	cfg.Seed1, cfg.Seed2 = 11, 12
+	g.newRun(cfg)
+	p := g.players[0]
+	p.Lives = maxLives
+
+	frame := 0
+	play := func(frames int) {
+		for ; frames > 0 && !g.showSplash; frames-- {
+			g.step([]PlayerInput{inputs[frame]})
+			frame++
+		}
+	}
+	play(300)
+
+	// get everything going that a save has to carry: an anomaly, a bomb
+	// going off and effects on the ship and the enemies
+	g.Anomaly.Activate(g.rngs[rngAnomaly], 3)
+	p.Effects.add(effectTimeSlow, 600)
+	p.Effects.add(effectDrone, 600)
+	g.enemyEffects.add(effectFreezeEnemies, 60)
+	p.Bombs = 1
+	bomb := inputs[frame]
+	bomb.Bomb = true
+	g.step([]PlayerInput{bomb})
+	frame++
+	if len(g.shockwaves) == 0 {
+		t.Fatal("the bomb didn't go off")
+	}
+
+	checked := map[string]bool{}
+	for g.Anomaly.IsActive && !g.showSplash {
+		switch {
+		case g.Anomaly.Incoming > 0:
+			checked["incoming"] = true
+		default:
+			checked["fading"] = true
+		}
+		if len(g.shockwaves) > 0 {
+			checked["shockwave"] = true
+		}
+		checkResumesIdentically(t, g, inputs[frame:frame+window])
+		frame += window
+		play(5)
+	}
+	for _, stage := range []string{"incoming", "fading", "shockwave"} {
+		if !checked[stage] {
+			t.Errorf("never saved with the %s", stage)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+// enemyScores is what each size of enemy is worth, biggest first. An enemy
+// scores the first row it's at least as big as, anything smaller than the
+// last row scores that, so there's no size that's silently worth nothing.
+var enemyScores = []struct {
+	MinRadius float64
+	Points    int
+}{
+	{40, 10},
+	{20, 20},
+	{10, 40},
+}
+
+// comboMultipliers[i] is the number of chained kills needed for a multiplier of i+1.
+var comboMultipliers = []int{0, 5, 10, 20, 35, 55, 80, 110}
+
+const (
+	comboWindow = 120 // frames after a kill to get the next one before the chain drops
+
+	frozenKillBonus = 0.5 // extra fraction of a kill's points when the enemies are frozen
+
+	nearMissDistance = 25 // pixels between an enemy's edge and the ship's centre
+	nearMissPoints   = 25
+)
+
+func enemyPoints(radius float64) int {
+	for _, row := range enemyScores {
+		if radius >= row.MinRadius {
+			return row.Points
+		}
+	}
+	return enemyScores[len(enemyScores)-1].Points
+}
+
+// multiplier is what p's points are being multiplied by.
+func (p *Player) multiplier() int {
+	m := 1
+	for i, kills := range comboMultipliers {
+		if p.Combo >= kills {
+			m = i + 1
+		}
+	}
+	return m
+}
+
+// tickCombo drops the chain once too long has passed without a kill.
+func (p *Player) tickCombo() {
+	if p.ComboTimer > 0 {
+		p.ComboTimer--
+		if p.ComboTimer == 0 {
+			p.Combo = 0
+		}
+	}
+}
+
+// breakCombo is for when the ship gets hit.
+func (p *Player) breakCombo() {
+	p.Combo = 0
+	p.ComboTimer = 0
+}
+
+// scoreKill gives p the points for destroying e. Kills with bullets chain
+// the combo, bombs get the multiplier but don't add to it.
+func (g *Game) scoreKill(p *Player, e *Enemy, chain, byBomb bool) {
+	if chain {
+		p.Combo++
+		p.ComboTimer = comboWindow
+	}
+	points := enemyPoints(e.Radius) * p.multiplier()
+	bonus := ""
+	if g.enemiesFrozen() {
+		points += int(float64(points) * frozenKillBonus)
+		bonus = "FROZEN"
+	}
+	g.addScore(p, ScoreChanged{Points: points, X: e.X, Y: e.Y, Bonus: bonus, ByBomb: byBomb})
+}
+
+// checkNearMiss pays out once per enemy for skimming past p without
+// touching it, multiplied like a kill. Coming into range only marks it, the
+// bonus is paid when it gets back out again, so one that goes on to hit the
+// ship never pays.
+func (g *Game) checkNearMiss(p *Player, e *Enemy) {
+	if e.Skimming && !g.players[e.SkimmedBy].Alive {
+		e.Skimming = false // the ship it was skimming has gone
+	}
+	if e.NearMissed || (e.Skimming && e.SkimmedBy != p.ID) {
+		return
+	}
+	dx := e.X - float64(p.Location.X)
+	dy := e.Y - float64(p.Location.Y)
+	reach := e.Radius + nearMissDistance
+	inRange := dx*dx+dy*dy < reach*reach
+
+	if e.Skimming {
+		if !inRange {
+			e.Skimming = false
+			e.NearMissed = true
+			g.addScore(p, ScoreChanged{Points: nearMissPoints * p.multiplier(), X: float64(p.Location.X), Y: float64(p.Location.Y), Bonus: "NEAR MISS"})
+		}
+		return
+	}
+	if inRange && e.HitTimer == 0 && !p.Effects.has(effectShield) && p.Invulnerable == 0 {
+		e.Skimming = true
+		e.SkimmedBy = p.ID
+	}
+}
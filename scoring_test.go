@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestNearMissPaysOnceClear(t *testing.T) {
+	newGame := func() (*Game, *Player, *Enemy) {
+		g := &Game{headless: true, settings: defaultSettings()}
+		g.newRun(g.newRunConfig(modeSurvival, 1))
+		p := g.players[0]
+		p.Invulnerable = 0
+		p.Lives = 1
+		e := &Enemy{Active: true, Radius: 20, Size: 2, X: float64(p.Location.X) + 200, Y: float64(p.Location.Y)}
+		g.enemies = []*Enemy{e}
+		return g, p, e
+	}
+	moveTo := func(g *Game, e *Enemy, dx float64) {
+		e.X = float64(g.players[0].Location.X) + dx
+		collisionDetectionPlayerAndEnemies(g)
+	}
+
+	t.Run("skims past", func(t *testing.T) {
+		g, p, e := newGame()
+		moveTo(g, e, e.Radius+nearMissDistance-5) // in range
+		if p.Score != 0 {
+			t.Fatalf("paid %d before it got clear", p.Score)
+		}
+		moveTo(g, e, e.Radius+nearMissDistance-1)
+		moveTo(g, e, 200)
+		if p.Score != nearMissPoints {
+			t.Fatalf("scored %d once clear, want %d", p.Score, nearMissPoints)
+		}
+		moveTo(g, e, e.Radius+nearMissDistance-5)
+		moveTo(g, e, 200)
+		if p.Score != nearMissPoints {
+			t.Fatalf("paid again on the way back, %d", p.Score)
+		}
+	})
+
+	t.Run("goes on to hit", func(t *testing.T) {
+		g, p, e := newGame()
+		moveTo(g, e, e.Radius+nearMissDistance-5)
+		moveTo(g, e, 0)
+		if p.Lives != 0 {
+			t.Fatalf("the enemy didn't hit")
+		}
+		p.Invulnerable = 0
+		moveTo(g, e, 200)
+		if p.Score != 0 {
+			t.Fatalf("a near miss that hit paid %d", p.Score)
+		}
+	})
+
+	t.Run("multiplied", func(t *testing.T) {
+		g, p, e := newGame()
+		p.Combo = 50
+		moveTo(g, e, e.Radius+nearMissDistance-5)
+		moveTo(g, e, 200)
+		if want := nearMissPoints * p.multiplier(); p.Score != want || want == nearMissPoints {
+			t.Fatalf("scored %d, want %d", p.Score, want)
+		}
+	})
+}
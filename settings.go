@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const settingsFile = "settings.json"
+
+// settingsVersion is bumped whenever the layout of Settings changes in a way
+// that needs an entry in settingsMigrations.
+const settingsVersion = 2
+
+type Difficulty int
+
+const (
+	difficultyEasy Difficulty = iota
+	difficultyNormal
+	difficultyHard
+)
+
+type DifficultyTuning struct {
+	Name       string
+	SpawnRate  float64 // multiplies the chance of an enemy spawning each frame
+	EnemySpeed float64 // pixels per frame
+	Lives      int     // spare ships each player starts with
+}
+
+var difficulties = []DifficultyTuning{
+	difficultyEasy:   {Name: "Easy", SpawnRate: 0.7, EnemySpeed: 2.2, Lives: 2},
+	difficultyNormal: {Name: "Normal", SpawnRate: 1, EnemySpeed: 3},
+	difficultyHard:   {Name: "Hard", SpawnRate: 1.5, EnemySpeed: 4},
+}
+
+type Settings struct {
+	Version        int                     `json:"version"`
+	Volume         float64                 `json:"volume"` // 0..1
+	PlayerKeys     [maxPlayers]KeyBindings `json:"playerKeys"`
+	Fullscreen     bool                    `json:"fullscreen"`
+	VSync          bool                    `json:"vsync"`
+	Difficulty     Difficulty              `json:"difficulty"`
+	ShowFPS        bool                    `json:"showFPS"`
+	Particles      ParticleDetail          `json:"particles"`
+	FriendlyFire   bool                    `json:"friendlyFire"`   // co-op bullets can hit the other ship
+	AutoFire       bool                    `json:"autoFire"`       // holding shoot keeps firing, otherwise it's a shot a press
+	BombInvincible bombRule                `json:"bombInvincible"` // what bombs do to invincible enemies
+	RunHistory     bool                    `json:"runHistory"`     // add each finished run's stats to history.jsonl
+	Accessibility  Accessibility           `json:"accessibility"`
+
+	// the online leaderboard is off unless there's a server to send scores to
+	LeaderboardURL string `json:"leaderboardURL"`
+	PlayerName     string `json:"playerName"`
+}
+
+func defaultSettings() Settings {
+	return Settings{
+		Version:       settingsVersion,
+		Volume:        0.8,
+		PlayerKeys:    [maxPlayers]KeyBindings{defaultKeyBindings(0), defaultKeyBindings(1)},
+		VSync:         true,
+		RunHistory:    true,
+		AutoFire:      true,
+		Difficulty:    difficultyNormal,
+		Particles:     particlesNormal,
+		Accessibility: defaultAccessibility(),
+		PlayerName:    "Player",
+	}
+}
+
+func (d Difficulty) tuning() DifficultyTuning {
+	if int(d) >= 0 && int(d) < len(difficulties) {
+		return difficulties[d]
+	}
+	return difficulties[difficultyNormal]
+}
+
+// settingsMigrations[v] upgrades a version v settings file to version v+1.
+// They work on the raw JSON so fields that were renamed or restructured can
+// be carried across before it's decoded into the current Settings.
+// Version 0 is a file written before settings were versioned, anything it
+// doesn't have just takes the default.
+var settingsMigrations = map[int]func(raw map[string]any) error{
+	// version 1 had a single set of keys, they become player one's
+	1: func(raw map[string]any) error {
+		if keys, ok := raw["keys"]; ok {
+			raw["playerKeys"] = []any{keys}
+			delete(raw, "keys")
+		}
+		return nil
+	},
+}
+
+func parseSettings(data []byte) (Settings, error) {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return Settings{}, fmt.Errorf("parsing settings: %w", err)
+	}
+
+	version := 0
+	if v, ok := raw["version"].(float64); ok {
+		version = int(v)
+	}
+	if version > settingsVersion {
+		return Settings{}, fmt.Errorf("settings are version %d but this build only understands up to %d", version, settingsVersion)
+	}
+
+	for ; version < settingsVersion; version++ {
+		if migrate, ok := settingsMigrations[version]; ok {
+			if err := migrate(raw); err != nil {
+				return Settings{}, fmt.Errorf("migrating settings from version %d: %w", version, err)
+			}
+		}
+	}
+	raw["version"] = settingsVersion
+
+	migrated, err := json.Marshal(raw)
+	if err != nil {
+		return Settings{}, err
+	}
+
+	// decode over the defaults so anything missing from the file keeps its default
+	s := defaultSettings()
+	if err := json.Unmarshal(migrated, &s); err != nil {
+		return Settings{}, fmt.Errorf("parsing settings: %w", err)
+	}
+
+	// a null in the file wipes out the default map, put back anything that's missing
+	for i, keys := range s.PlayerKeys {
+		if keys == nil {
+			keys = KeyBindings{}
+			s.PlayerKeys[i] = keys
+		}
+		for a, key := range defaultKeyBindings(i) {
+			if _, ok := keys[a]; !ok {
+				keys[a] = key
+			}
+		}
+	}
+	return s, nil
+}
+
+// loadSettings never fails, if there's nothing saved or it can't be read
+// we log why and carry on with the defaults.
+func loadSettings() Settings {
+	data, err := readStorage(settingsFile)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("reading settings: %v", err)
+		}
+		return defaultSettings()
+	}
+
+	s, err := parseSettings(data)
+	if err != nil {
+		log.Print(err)
+		return defaultSettings()
+	}
+	return s
+}
+
+func saveSettings(s Settings) {
+	s.Version = settingsVersion
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		log.Printf("saving settings: %v", err)
+		return
+	}
+	if err := writeStorage(settingsFile, data); err != nil {
+		log.Printf("saving settings: %v", err)
+	}
+}
+
+// applySettings pushes the settings out to ebiten and the other systems that keep their own copy.
+func (g *Game) applySettings() {
+	ebiten.SetFullscreen(g.settings.Fullscreen)
+	ebiten.SetVsyncEnabled(g.settings.VSync)
+	soundVolume = g.settings.Volume
+	g.particles.Budget = g.settings.Particles.budget()
+	g.applyAccessibility()
+}
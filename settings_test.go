@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func TestParseSettings(t *testing.T) {
+	f, err := json.Marshal(ebiten.KeyF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withFire := func(player int) KeyBindings {
+		keys := defaultKeyBindings(player)
+		keys[actionFire] = ebiten.KeyF
+		return keys
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		keys    [maxPlayers]KeyBindings
+		volume  float64
+		wantErr string // part of the error, if there should be one
+	}{
+		{
+			name:   "version 0 with one set of keys",
+			data:   fmt.Sprintf(`{"volume": 0.3, "keys": {"fire": %s}}`, f),
+			keys:   [maxPlayers]KeyBindings{withFire(0), defaultKeyBindings(1)},
+			volume: 0.3,
+		},
+		{
+			name:   "version 1 with one set of keys",
+			data:   fmt.Sprintf(`{"version": 1, "volume": 0.3, "keys": {"fire": %s}}`, f),
+			keys:   [maxPlayers]KeyBindings{withFire(0), defaultKeyBindings(1)},
+			volume: 0.3,
+		},
+		{
+			name:   "null player keys",
+			data:   `{"version": 2, "playerKeys": null}`,
+			keys:   [maxPlayers]KeyBindings{defaultKeyBindings(0), defaultKeyBindings(1)},
+			volume: 0.8,
+		},
+		{
+			name:   "one player's keys null, the other's partly set",
+			data:   fmt.Sprintf(`{"version": 2, "playerKeys": [null, {"fire": %s}]}`, f),
+			keys:   [maxPlayers]KeyBindings{defaultKeyBindings(0), withFire(1)},
+			volume: 0.8,
+		},
+		{
+			name:    "from a newer build",
+			data:    fmt.Sprintf(`{"version": %d}`, settingsVersion+1),
+			wantErr: fmt.Sprintf("version %d", settingsVersion+1),
+		},
+		{
+			name:    "not JSON",
+			data:    `{"version": `,
+			wantErr: "parsing settings",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parseSettings([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one about %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range tt.keys {
+				if !maps.Equal(s.PlayerKeys[i], tt.keys[i]) {
+					t.Errorf("player %d keys are %v, want %v", i+1, s.PlayerKeys[i], tt.keys[i])
+				}
+			}
+			if s.Version != settingsVersion {
+				t.Errorf("version %d, want %d", s.Version, settingsVersion)
+			}
+			if s.Volume != tt.volume {
+				t.Errorf("volume %v, want %v", s.Volume, tt.volume)
+			}
+			// everything the file didn't have is left at its default
+			want := defaultSettings()
+			if s.AutoFire != want.AutoFire || s.Difficulty != want.Difficulty || s.Particles != want.Particles ||
+				s.VSync != want.VSync || s.PlayerName != want.PlayerName || s.Accessibility != want.Accessibility {
+				t.Errorf("defaults lost: got %+v", s)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"strings"
+	"time"
+)
+
+// historyFile gets a line of JSON for every finished run, when the
+// settings allow it, for working out lifetime stats.
+const historyFile = "history.jsonl"
+
+// saveStatsFile goes with the autosave so a continued run keeps its stats.
+const saveStatsFile = "save-stats.json"
+
+// PlayerStats is one ship's run, collected from the game's events.
+type PlayerStats struct {
+	Score             int            `json:"score"`
+	FramesAlive       int            `json:"framesAlive"`
+	ShotsFired        int            `json:"shotsFired"`
+	ShotsHit          int            `json:"shotsHit"`
+	Kills             map[string]int `json:"kills"`    // by enemySize
+	Powerups          map[string]int `json:"powerups"` // by powerupDef.Name
+	BombsUsed         int            `json:"bombsUsed"`
+	BombPoints        int            `json:"bombPoints"` // scored by bomb kills
+	AnomaliesSurvived int            `json:"anomaliesSurvived"`
+	PeakMultiplier    int            `json:"peakMultiplier"`
+}
+
+// RunStats is a whole run, and a line of the history file.
+type RunStats struct {
+	Ended      time.Time     `json:"ended"`
+	Mode       string        `json:"mode"`
+	Difficulty string        `json:"difficulty"`
+	Daily      string        `json:"daily,omitempty"`
+	Network    bool          `json:"network,omitempty"`
+	Players    []PlayerStats `json:"players"`
+
+	hit map[*Bullet]bool // bullets that have hit something, so one that goes through several only counts once
+}
+
+func newRunStats(players int) RunStats {
+	s := RunStats{hit: map[*Bullet]bool{}}
+	for i := 0; i < players; i++ {
+		s.Players = append(s.Players, PlayerStats{
+			Kills:          map[string]int{},
+			Powerups:       map[string]int{},
+			PeakMultiplier: 1,
+		})
+	}
+	return s
+}
+
+// forgetBullet is called as b is taken out of the game, so hit doesn't
+// keep every bullet of the run around.
+func (s *RunStats) forgetBullet(b *Bullet) {
+	delete(s.hit, b)
+}
+
+func enemySize(radius float64) string {
+	switch {
+	case radius >= 40:
+		return "large"
+	case radius >= 20:
+		return "medium"
+	}
+	return "small"
+}
+
+// subscribeStats counts everything for the stats as it happens.
+func (g *Game) subscribeStats() {
+	player := func(p *Player) *PlayerStats {
+		if p == nil || p.ID >= len(g.stats.Players) {
+			return &PlayerStats{} // somewhere harmless to count into
+		}
+		return &g.stats.Players[p.ID]
+	}
+
+	bus := &g.events
+	bus.shotFired.subscribe(func(e ShotFired) {
+		player(e.Player).ShotsFired++
+	})
+	bus.enemyHit.subscribe(func(e EnemyHit) {
+		// bouncing off an invincible enemy isn't a hit
+		if e.Bullet != nil && !e.Blocked && !g.stats.hit[e.Bullet] {
+			g.stats.hit[e.Bullet] = true
+			player(e.Player).ShotsHit++
+		}
+	})
+	bus.enemyDestroyed.subscribe(func(e EnemyDestroyed) {
+		player(e.Player).Kills[enemySize(e.Enemy.Radius)]++
+	})
+	bus.powerupCollected.subscribe(func(e PowerupCollected) {
+		player(e.Player).Powerups[powerupInfo(e.Powerup.Type).Name]++
+	})
+	bus.bombDetonated.subscribe(func(e BombDetonated) {
+		player(e.Player).BombsUsed++
+	})
+	bus.scoreChanged.subscribe(func(e ScoreChanged) {
+		s := player(e.Player)
+		s.PeakMultiplier = max(s.PeakMultiplier, e.Multiplier)
+		if e.ByBomb {
+			s.BombPoints += e.Points
+		}
+	})
+	bus.anomalyStruck.subscribe(func(e AnomalyStruck) {
+		if e.Survived {
+			player(e.Player).AnomaliesSurvived++
+		}
+	})
+}
+
+// finishStats fills in the totals when the run ends and adds it to the history.
+func (g *Game) finishStats() {
+	s := &g.stats
+	for _, p := range g.players {
+		if p.ID < len(s.Players) {
+			s.Players[p.ID].Score = p.Score
+			s.Players[p.ID].FramesAlive = p.FramesAlive
+		}
+	}
+	s.Ended = time.Now().UTC()
+	s.Mode = "survival"
+	if g.mode == modeVersus {
+		s.Mode = "versus"
+	}
+	s.Difficulty = boardName(g.difficulty)
+	s.Daily = g.daily
+	s.Network = g.network != nil
+
+	if g.settings.RunHistory {
+		appendHistory(*s)
+	}
+}
+
+func appendHistory(s RunStats) {
+	line, err := json.Marshal(s)
+	if err != nil {
+		log.Printf("saving run history: %v", err)
+		return
+	}
+	history, err := readStorage(historyFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("reading run history: %v", err)
+		return // don't write over a history we couldn't read
+	}
+	history = append(append(history, line...), '\n')
+	if err := writeStorage(historyFile, history); err != nil {
+		log.Printf("saving run history: %v", err)
+	}
+}
+
+// saveStats and loadStats keep the stats of an autosaved run alongside it.
+// They aren't in the save itself because that's the simulation's state,
+// which replays and network games check, and the stats are only watching.
+func (g *Game) saveStats() {
+	data, err := json.Marshal(g.stats)
+	if err == nil {
+		err = writeStorage(saveStatsFile, data)
+	}
+	if err != nil {
+		log.Printf("saving stats: %v", err)
+	}
+}
+
+func (g *Game) loadStats(players int) {
+	g.stats = newRunStats(players)
+	data, err := readStorage(saveStatsFile)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("reading stats: %v", err)
+		}
+		return
+	}
+	var s RunStats
+	if err := json.Unmarshal(data, &s); err != nil || len(s.Players) != players {
+		log.Printf("reading stats: they don't go with the save")
+		return
+	}
+	for i := range s.Players {
+		// maps that were empty come back nil
+		if s.Players[i].Kills == nil {
+			s.Players[i].Kills = map[string]int{}
+		}
+		if s.Players[i].Powerups == nil {
+			s.Players[i].Powerups = map[string]int{}
+		}
+	}
+	s.hit = map[*Bullet]bool{}
+	g.stats = s
+}
+
+// statsSummary is the game over screen's breakdown of the run, with a
+// column per player in co-op.
+func (g *Game) statsSummary() []string {
+	players := g.stats.Players
+	row := func(label string, value func(p PlayerStats) string) string {
+		values := make([]string, len(players))
+		for i, p := range players {
+			values[i] = value(p)
+		}
+		return label + ": " + strings.Join(values, "  |  ")
+	}
+
+	lines := []string{
+		row("Time alive", func(p PlayerStats) string {
+			secs := p.FramesAlive / 60
+			return fmt.Sprintf("%d:%02d", secs/60, secs%60)
+		}),
+		row("Shots fired", func(p PlayerStats) string {
+			accuracy := 0
+			if p.ShotsFired > 0 {
+				accuracy = p.ShotsHit * 100 / p.ShotsFired
+			}
+			return fmt.Sprintf("%d, %d%% accurate", p.ShotsFired, accuracy)
+		}),
+		row("Destroyed", func(p PlayerStats) string {
+			return fmt.Sprintf("%d large, %d medium, %d small", p.Kills["large"], p.Kills["medium"], p.Kills["small"])
+		}),
+		row("Powerups", func(p PlayerStats) string {
+			var got []string
+			for t := 1; t < numPowerupTypes; t++ {
+				if n := p.Powerups[powerupInfo(t).Name]; n > 0 {
+					got = append(got, fmt.Sprintf("%d %s", n, powerupInfo(t).Name))
+				}
+			}
+			if len(got) == 0 {
+				return "none"
+			}
+			return strings.Join(got, ", ")
+		}),
+		row("Bombs used", func(p PlayerStats) string {
+			if p.BombsUsed == 0 {
+				return "0"
+			}
+			return fmt.Sprintf("%d, %d points", p.BombsUsed, p.BombPoints)
+		}),
+		row("Anomalies survived", func(p PlayerStats) string { return fmt.Sprint(p.AnomaliesSurvived) }),
+		row("Peak multiplier", func(p PlayerStats) string { return fmt.Sprintf("x%d", p.PeakMultiplier) }),
+	}
+	return lines
+}
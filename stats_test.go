@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestShotsHitCountsEachBulletOnce(t *testing.T) {
+	g := &Game{headless: true, settings: defaultSettings()}
+	g.subscribeStats()
+	g.newRun(g.newRunConfig(modeSurvival, 1))
+	p := g.players[0]
+
+	shoot := func(e *Enemy) {
+		g.enemies = []*Enemy{e}
+		g.bullets = []*Bullet{{X: e.X, Y: e.Y, VY: -1000, Active: true}}
+		collisionDetectionBulletsAndEnemies(g)
+		handleShooting(g) // it's off the screen after this
+	}
+
+	shoot(&Enemy{X: 100, Y: 100, Radius: 10, Active: true, IsInvincible: true})
+	if got := g.stats.Players[0].ShotsHit; got != 0 {
+		t.Fatalf("a shot blocked by an invincible enemy counted as %d hits", got)
+	}
+	shoot(&Enemy{X: 100, Y: 100, Radius: 10, Active: true})
+	if got := g.stats.Players[0].ShotsHit; got != 1 {
+		t.Fatalf("%d hits after hitting one enemy, want 1", got)
+	}
+
+	// an invincible bullet going through two enemies is still one shot
+	g.enemies = []*Enemy{
+		{X: 100, Y: 100, Radius: 10, Active: true},
+		{X: 100, Y: 105, Radius: 10, Active: true},
+	}
+	p.Effects.add(effectInvincibleBullets, 300)
+	b := &Bullet{X: 100, Y: 102, VY: -1000, Active: true}
+	g.bullets = []*Bullet{b}
+	collisionDetectionBulletsAndEnemies(g)
+	collisionDetectionBulletsAndEnemies(g)
+	if got := g.stats.Players[0].ShotsHit; got != 2 {
+		t.Fatalf("%d hits after one bullet went through two enemies, want 2", got)
+	}
+
+	handleShooting(g)
+	if len(g.stats.hit) != 0 {
+		t.Fatalf("still remembering %d bullets that have gone", len(g.stats.hit))
+	}
+}
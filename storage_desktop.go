@@ -0,0 +1,57 @@
+//go:build !js
+
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// storagePath is where a named blob of data lives on disk,
+// e.g. ~/.config/space-shooter/settings.json on linux.
+func storagePath(name string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "space-shooter", name), nil
+}
+
+// readStorage returns an error satisfying errors.Is(err, fs.ErrNotExist) if nothing has been stored yet.
+func readStorage(name string) ([]byte, error) {
+	path, err := storagePath(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+func writeStorage(name string, data []byte) error {
+	path, err := storagePath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// write to a temp file and rename so a crash never leaves half a file behind
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func deleteStorage(name string) error {
+	path, err := storagePath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
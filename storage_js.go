@@ -0,0 +1,59 @@
+//go:build js
+
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"syscall/js"
+)
+
+// in the browser everything goes in localStorage, prefixed so we don't
+// collide with anything else served from the same origin
+const storagePrefix = "space-shooter/"
+
+func localStorage() (js.Value, error) {
+	storage := js.Global().Get("localStorage")
+	if !storage.Truthy() {
+		return js.Value{}, fmt.Errorf("localStorage is not available")
+	}
+	return storage, nil
+}
+
+// readStorage returns an error satisfying errors.Is(err, fs.ErrNotExist) if nothing has been stored yet.
+func readStorage(name string) ([]byte, error) {
+	storage, err := localStorage()
+	if err != nil {
+		return nil, err
+	}
+	value := storage.Call("getItem", storagePrefix+name)
+	if value.IsNull() || value.IsUndefined() {
+		return nil, fs.ErrNotExist
+	}
+	return []byte(value.String()), nil
+}
+
+func writeStorage(name string, data []byte) (err error) {
+	storage, err := localStorage()
+	if err != nil {
+		return err
+	}
+
+	// setItem throws if the quota is exceeded or storage is disabled
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("writing %s to localStorage: %v", name, r)
+		}
+	}()
+	storage.Call("setItem", storagePrefix+name, string(data))
+	return nil
+}
+
+func deleteStorage(name string) error {
+	storage, err := localStorage()
+	if err != nil {
+		return err
+	}
+	storage.Call("removeItem", storagePrefix+name)
+	return nil
+}
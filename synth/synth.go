@@ -0,0 +1,194 @@
+// Package synth generates retro sound effects from a handful of parameters,
+// in the spirit of sfxr. Effects are rendered once into PCM buffers so the
+// game can play them back without shipping any sample files.
+package synth
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+type Waveform int
+
+const (
+	Square Waveform = iota
+	Triangle
+	Sawtooth
+	Noise
+)
+
+var waveformNames = map[Waveform]string{
+	Square:   "square",
+	Triangle: "triangle",
+	Sawtooth: "sawtooth",
+	Noise:    "noise",
+}
+
+func (w Waveform) String() string {
+	if name, ok := waveformNames[w]; ok {
+		return name
+	}
+	return fmt.Sprintf("Waveform(%d)", int(w))
+}
+
+func (w Waveform) MarshalText() ([]byte, error) {
+	name, ok := waveformNames[w]
+	if !ok {
+		return nil, fmt.Errorf("synth: unknown waveform %d", int(w))
+	}
+	return []byte(name), nil
+}
+
+func (w *Waveform) UnmarshalText(text []byte) error {
+	for wave, name := range waveformNames {
+		if strings.EqualFold(name, string(text)) {
+			*w = wave
+			return nil
+		}
+	}
+	return fmt.Errorf("synth: unknown waveform %q", string(text))
+}
+
+// Params describes a single effect. Times are in seconds, frequencies in Hz.
+type Params struct {
+	Name string   `json:"name"`
+	Wave Waveform `json:"wave"`
+
+	Frequency    float64 `json:"frequency"`    // starting pitch
+	Slide        float64 `json:"slide"`        // pitch change in Hz per second, negative slides down
+	MinFrequency float64 `json:"minFrequency"` // the slide stops here
+	MaxFrequency float64 `json:"maxFrequency"` // and here, zero means no limit
+	Duty         float64 `json:"duty"`         // square wave duty cycle, 0.5 if unset
+
+	// ADSR envelope
+	Attack       float64 `json:"attack"`
+	Decay        float64 `json:"decay"`
+	Sustain      float64 `json:"sustain"` // how long to hold at SustainLevel
+	SustainLevel float64 `json:"sustainLevel"`
+	Release      float64 `json:"release"`
+
+	Volume float64 `json:"volume"` // 0..1
+	Seed   uint32  `json:"seed"`   // seeds the noise generator so renders are repeatable
+}
+
+// Duration is the total length of the effect in seconds.
+func (p Params) Duration() float64 {
+	return p.Attack + p.Decay + p.Sustain + p.Release
+}
+
+// envelope returns the amplitude (0..1) at time t seconds into the effect.
+func (p Params) envelope(t float64) float64 {
+	switch {
+	case t < p.Attack:
+		return t / p.Attack
+	case t < p.Attack+p.Decay:
+		progress := (t - p.Attack) / p.Decay
+		return 1 - progress*(1-p.SustainLevel)
+	case t < p.Attack+p.Decay+p.Sustain:
+		return p.SustainLevel
+	case t < p.Duration():
+		progress := (t - p.Attack - p.Decay - p.Sustain) / p.Release
+		return p.SustainLevel * (1 - progress)
+	}
+	return 0
+}
+
+// ParsePresets decodes a JSON array of effect parameters.
+func ParsePresets(data []byte) ([]Params, error) {
+	var presets []Params
+	if err := json.Unmarshal(data, &presets); err != nil {
+		return nil, fmt.Errorf("synth: parsing presets: %w", err)
+	}
+	for _, p := range presets {
+		if p.Name == "" {
+			return nil, fmt.Errorf("synth: preset without a name")
+		}
+		if p.Frequency <= 0 {
+			return nil, fmt.Errorf("synth: preset %q needs a positive frequency", p.Name)
+		}
+	}
+	return presets, nil
+}
+
+// Render generates mono samples in the range -1..1 for the given effect.
+// The same Params always produce the same samples.
+func Render(p Params, sampleRate int) []float64 {
+	n := int(p.Duration() * float64(sampleRate))
+	samples := make([]float64, n)
+
+	duty := p.Duty
+	if duty <= 0 || duty >= 1 {
+		duty = 0.5
+	}
+
+	// xorshift needs a non-zero state
+	noiseState := p.Seed
+	if noiseState == 0 {
+		noiseState = 0x9e3779b9
+	}
+	noiseValue := 0.0
+
+	freq := p.Frequency
+	phase := 0.0
+	dt := 1 / float64(sampleRate)
+
+	for i := range samples {
+		var v float64
+		switch p.Wave {
+		case Square:
+			if phase < duty {
+				v = 1
+			} else {
+				v = -1
+			}
+		case Triangle:
+			v = 4*math.Abs(phase-0.5) - 1
+		case Sawtooth:
+			v = 2*phase - 1
+		case Noise:
+			v = noiseValue
+		}
+
+		samples[i] = v * p.envelope(float64(i)*dt) * p.Volume
+
+		phase += freq * dt
+		if phase >= 1 {
+			phase -= math.Floor(phase)
+			// noise picks a new level once per period, so the pitch
+			// controls how rough it sounds
+			noiseState ^= noiseState << 13
+			noiseState ^= noiseState >> 17
+			noiseState ^= noiseState << 5
+			noiseValue = float64(noiseState)/math.MaxUint32*2 - 1
+		}
+
+		freq += p.Slide * dt
+		if freq < p.MinFrequency {
+			freq = p.MinFrequency
+		}
+		if p.MaxFrequency > 0 && freq > p.MaxFrequency {
+			freq = p.MaxFrequency
+		}
+		if freq < 1 {
+			freq = 1
+		}
+	}
+
+	return samples
+}
+
+// EncodeStereo16 converts samples to 16 bit little endian stereo PCM,
+// the format ebiten's audio players expect.
+func EncodeStereo16(samples []float64) []byte {
+	buf := make([]byte, len(samples)*4)
+	for i, s := range samples {
+		s = math.Max(-1, math.Min(1, s))
+		v := uint16(int16(s * math.MaxInt16))
+		binary.LittleEndian.PutUint16(buf[i*4:], v)
+		binary.LittleEndian.PutUint16(buf[i*4+2:], v)
+	}
+	return buf
+}
@@ -0,0 +1,75 @@
+package synth
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+const testRate = 44100
+
+var testPatches = []Params{
+	{Name: "laser", Wave: Square, Frequency: 880, Slide: -1500, MinFrequency: 200, Duty: 0.25,
+		Attack: 0.01, Decay: 0.05, Sustain: 0.05, SustainLevel: 0.5, Release: 0.1, Volume: 0.8},
+	{Name: "hum", Wave: Triangle, Frequency: 110,
+		Attack: 0.02, Decay: 0.02, Sustain: 0.2, SustainLevel: 0.6, Release: 0.05, Volume: 0.5},
+	{Name: "rise", Wave: Sawtooth, Frequency: 220, Slide: 2000, MaxFrequency: 660,
+		Attack: 0.005, Decay: 0.1, Sustain: 0, SustainLevel: 0.3, Release: 0.1, Volume: 1},
+	{Name: "boom", Wave: Noise, Frequency: 1200, Slide: -2000, MinFrequency: 100,
+		Attack: 0.001, Decay: 0.2, Sustain: 0.1, SustainLevel: 0.4, Release: 0.3, Volume: 0.9, Seed: 42},
+}
+
+func TestRenderLength(t *testing.T) {
+	for _, p := range testPatches {
+		want := int(p.Duration() * testRate)
+		if got := len(Render(p, testRate)); got != want {
+			t.Errorf("%s: %d samples, want %d", p.Name, got, want)
+		}
+		if got := len(EncodeStereo16(Render(p, testRate))); got != want*4 {
+			t.Errorf("%s: %d bytes of PCM, want %d", p.Name, got, want*4)
+		}
+	}
+}
+
+func TestRenderStaysInsideTheEnvelope(t *testing.T) {
+	for _, p := range testPatches {
+		samples := Render(p, testRate)
+		peak := 0.0
+		for i, s := range samples {
+			limit := p.envelope(float64(i)/testRate)*p.Volume + 1e-9
+			if math.Abs(s) > limit {
+				t.Fatalf("%s: sample %d is %v, over the envelope's %v", p.Name, i, s, limit)
+			}
+			peak = max(peak, math.Abs(s))
+		}
+		if peak > p.Volume {
+			t.Errorf("%s: peak %v is over the volume %v", p.Name, peak, p.Volume)
+		}
+		// the attack is shorter than a period for some of these, so only
+		// ask that it gets reasonably close to full volume somewhere
+		if peak < p.Volume*0.5 {
+			t.Errorf("%s: peak %v never gets near the volume %v", p.Name, peak, p.Volume)
+		}
+		if end := samples[len(samples)-1]; math.Abs(end) > p.Volume*0.01 {
+			t.Errorf("%s: still at %v when the release finishes", p.Name, end)
+		}
+	}
+}
+
+func TestRenderIsRepeatable(t *testing.T) {
+	for _, p := range testPatches {
+		a := EncodeStereo16(Render(p, testRate))
+		b := EncodeStereo16(Render(p, testRate))
+		if !bytes.Equal(a, b) {
+			t.Errorf("%s: two renders came out different", p.Name)
+		}
+	}
+
+	// and the seed is what picks the noise
+	boom := testPatches[len(testPatches)-1]
+	other := boom
+	other.Seed++
+	if bytes.Equal(EncodeStereo16(Render(boom, testRate)), EncodeStereo16(Render(other, testRate))) {
+		t.Errorf("different seeds rendered the same noise")
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/font/basicfont"
+)
+
+const (
+	touchThumbSize   = 60.0 // how big the stick is on the actual screen, before it's scaled up to the game's pixels
+	touchMargin      = 30.0
+	touchDeadZone    = 0.25 // of the stick's reach, less than this and the ship is left alone
+	touchThrustPoint = 0.6  // pushed further than this it thrusts as well as turning
+)
+
+// touchButton is a round on-screen button, or the base of the stick.
+type touchButton struct {
+	X, Y, R float64
+	Label   string
+}
+
+func (b touchButton) contains(x, y int) bool {
+	return math.Hypot(float64(x)-b.X, float64(y)-b.Y) < b.R
+}
+
+func (b touchButton) draw(screen *ebiten.Image, held bool, width float32) {
+	fill := color.RGBA{255, 255, 255, 40}
+	if held {
+		fill = color.RGBA{255, 255, 255, 110}
+	}
+	vector.DrawFilledCircle(screen, float32(b.X), float32(b.Y), float32(b.R), fill, true)
+	vector.StrokeCircle(screen, float32(b.X), float32(b.Y), float32(b.R), width, color.RGBA{255, 255, 255, 160}, true)
+	if b.Label != "" {
+		bounds := text.BoundString(basicfont.Face7x13, b.Label)
+		text.Draw(screen, b.Label, basicfont.Face7x13, int(b.X)-bounds.Dx()/2, int(b.Y)+bounds.Dy()/2, color.White)
+	}
+}
+
+type touchLayout struct {
+	stick, fire, bomb, pause touchButton
+}
+
+// touchControls are the on-screen stick and buttons for phones and tablets.
+// They show up the first time the screen is touched and drive player one
+// alongside the keyboard. Each finger is tracked separately, so steering
+// and shooting at once works.
+type touchControls struct {
+	shown      bool
+	stick      ebiten.TouchID
+	stickHeld  bool
+	stickX     float64 // where the stick is pushed, -1..1 each way
+	stickY     float64
+	fire, bomb bool // held this frame
+	pause      bool
+	was        struct{ fire, bomb, pause bool } // last frame, for the edges
+	windowW    int                              // the window, for working out the layout
+	windowH    int
+}
+
+// layout places the controls in the game's 1280x960. The game is scaled to
+// fit the window, so they're scaled back up to stay thumb sized, which
+// makes them bigger on a phone held upright. Upright, the bomb goes beside
+// fire rather than above it, there's less room over the letterboxed game.
+func (t *touchControls) layout() touchLayout {
+	screenWidth, screenHeight := 1280.0, 960.0
+	scale := 1.0
+	if t.windowW > 0 && t.windowH > 0 {
+		scale = math.Max(screenWidth/float64(t.windowW), screenHeight/float64(t.windowH))
+	}
+	r := min(max(touchThumbSize*scale, 60), 200)
+	margin := touchMargin * scale
+	portrait := t.windowH > t.windowW
+
+	l := touchLayout{
+		stick: touchButton{X: margin + r, Y: screenHeight - margin - r, R: r},
+		fire:  touchButton{X: screenWidth - margin - r*0.7, Y: screenHeight - margin - r*0.7, R: r * 0.7, Label: "FIRE"},
+		pause: touchButton{X: screenWidth - margin/2 - r*0.3, Y: margin/2 + r*0.3, R: r * 0.3, Label: "II"},
+	}
+	l.bomb = touchButton{X: l.fire.X, Y: l.fire.Y - r*1.6, R: r * 0.5, Label: "BOMB"}
+	if portrait {
+		l.bomb.X, l.bomb.Y = l.fire.X-r*1.6, l.fire.Y
+	}
+	return l
+}
+
+// update follows every finger on the screen. Called once a frame, before anything reads it.
+func (t *touchControls) update() {
+	started := inpututil.AppendJustPressedTouchIDs(nil)
+	if len(started) > 0 {
+		t.shown = true
+	}
+	l := t.layout()
+
+	if t.stickHeld && inpututil.IsTouchJustReleased(t.stick) {
+		t.stickHeld = false
+	}
+	for _, id := range started {
+		x, y := ebiten.TouchPosition(id)
+		// a bit of slack round the stick, nobody's thumb lands dead centre
+		grab := l.stick
+		grab.R *= 1.5
+		if !t.stickHeld && grab.contains(x, y) {
+			t.stick, t.stickHeld = id, true
+		}
+	}
+	t.stickX, t.stickY = 0, 0
+	if t.stickHeld {
+		x, y := ebiten.TouchPosition(t.stick)
+		dx := (float64(x) - l.stick.X) / l.stick.R
+		dy := (float64(y) - l.stick.Y) / l.stick.R
+		if d := math.Hypot(dx, dy); d > 1 {
+			dx, dy = dx/d, dy/d
+		}
+		t.stickX, t.stickY = dx, dy
+	}
+
+	// a finger can slide onto or off a button, it's whatever is under one now
+	t.was.fire, t.was.bomb, t.was.pause = t.fire, t.bomb, t.pause
+	t.fire, t.bomb, t.pause = false, false, false
+	for _, id := range ebiten.AppendTouchIDs(nil) {
+		if t.stickHeld && id == t.stick {
+			continue
+		}
+		x, y := ebiten.TouchPosition(id)
+		t.fire = t.fire || l.fire.contains(x, y)
+		t.bomb = t.bomb || l.bomb.contains(x, y)
+		t.pause = t.pause || l.pause.contains(x, y)
+	}
+}
+
+// input is what the controls are asking p's ship to do. The stick points
+// the way the ship should face, it turns towards that, and pushing it most
+// of the way out thrusts as well.
+func (t *touchControls) input(p *Player, autoFire bool) PlayerInput {
+	var in PlayerInput
+	if mag := math.Hypot(t.stickX, t.stickY); mag > touchDeadZone {
+		want := math.Atan2(t.stickX, -t.stickY) // an angle of 0 is straight up, same as movePlayerShip
+		diff := math.Remainder(want-p.ShipAngle, 2*math.Pi)
+		in.RotateLeft = diff < -rotateSpeed
+		in.RotateRight = diff > rotateSpeed
+		in.Thrust = mag > touchThrustPoint
+	}
+	in.Fire = t.fire && (autoFire || !t.was.fire)
+	in.Bomb = t.bomb && !t.was.bomb
+	return in
+}
+
+func (t *touchControls) pausePressed() bool {
+	return t.pause && !t.was.pause
+}
+
+// withTouch adds the touch controls to player one's keyboard input.
+func (g *Game) withTouch(p *Player, keys PlayerInput) PlayerInput {
+	if !g.touch.shown {
+		return keys
+	}
+	return inputFromBits(keys.bits() | g.touch.input(p, g.settings.AutoFire).bits())
+}
+
+func (g *Game) drawTouchControls(screen *ebiten.Image) {
+	if !g.touch.shown || g.showSplash || len(g.menus) > 0 {
+		return
+	}
+	t := &g.touch
+	l := t.layout()
+	width := g.settings.Accessibility.palette().StrokeWidth
+
+	l.stick.draw(screen, t.stickHeld, width)
+	knob := touchButton{X: l.stick.X + t.stickX*l.stick.R, Y: l.stick.Y + t.stickY*l.stick.R, R: l.stick.R * 0.4}
+	knob.draw(screen, t.stickHeld, width)
+
+	l.fire.draw(screen, t.fire, width)
+	if len(g.players) > 0 && g.players[0].Bombs > 0 {
+		l.bomb.draw(screen, t.bomb, width)
+	}
+	l.pause.draw(screen, t.pause, width)
+}
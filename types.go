@@ -1,5 +1,7 @@
 package main
 
+import "math/rand/v2"
+
 type Point struct {
 	X int
 	Y int
@@ -13,53 +15,82 @@ type Enemy struct {
 	Size         int
 	HitTimer     int
 	IsInvincible bool
+	NearMissed   bool // it's had its near miss, paid or spoiled by touching a ship
+	Skimming     bool // inside a ship's near miss range, it pays out if it gets clear without touching
+	SkimmedBy    int  // the ID of the ship it's skimming
 }
 
 type Bullet struct {
 	X, Y   float64
 	VX, VY float64
 	Active bool
+	Owner  int // ID of the player who fired it
 }
 
 type Anomaly struct {
 	fadeTimer        int
 	fadeFlashTimer   int
 	flashing         bool
-	SafeRadius       float64
-	SafeX            float64
-	SafeY            float64
+	Kind             anomalyKind
+	Zones            []SafeZone
+	DriftX, DriftY   float64 // how a moving safe zone is drifting
 	Alpha            uint8
 	IsActive         bool
 	Incoming         int
 	lastAnomalyScore int
 }
 
-// todo: refactor this a bit to use more entities to collectively store data
-// e.g. maxSpeed, shipAngle, velocity, etc. could be in a separate player struct
 type Game struct {
-	playerLocation         Point
-	shipAngle              float64 // in radians
-	velocity               float64
-	maxSpeed               float64
-	enemies                []*Enemy
-	bullets                []*Bullet
-	shootCooldown          int
-	score                  int
-	showSplash             bool
-	powerups               []*Powerup
-	hasShield              bool
-	shieldTimer            int
-	bombs                  int
-	flashTimer             int
-	invincibleBulletsTimer int
-	frozenEnemiesTimer     int
-	previousScore          int
-	Anomaly                Anomaly
-	invincibleEnemiesTimer int
+	players        []*Player
+	enemies        []*Enemy
+	bullets        []*Bullet
+	showSplash     bool
+	powerups       []*Powerup
+	flashTimer     int
+	previousScores []int // one per player from the last run, shown on the game over screen
+	Anomaly        Anomaly
+	enemyEffects   ActiveEffects // the timed powerups on all the enemies, freeze and the like
+	dropDrought    int           // enemies destroyed since the last drop
+	shockwaves     []*Shockwave  // bombs still going off
+	particles      ParticleSystem
+	effects        Effects
+	settings       Settings
+	menus          []*Menu
+	touch          touchControls
+	pendingInputs  []PlayerInput // read since the last step, presses are kept until one takes them
+
+	// everything random in the simulation comes from rngs so a run can be
+	// saved and resumed exactly, rngSources are kept to read back their state
+	rngs           [numRNGStreams]*rand.Rand
+	rngSources     [numRNGStreams]*rand.PCG
+	difficulty     Difficulty // fixed for the whole run, from the settings when it started
+	friendlyFire   bool       // co-op bullets can hit the other ship, also fixed for the run
+	bombInvincible bombRule   // what bombs do to invincible enemies, fixed for the run too
+	daily          string     // the date if this is a daily challenge
+	practice       bool       // a daily challenge that won't be scored
+
+	mode      GameMode
+	versus    Versus  // only used in versus mode
+	lastMatch *Versus // the versus match that just finished, for the summary screen
+
+	network   *networkGame // nil unless playing with someone on another machine
+	netStatus string       // why the last network game ended, or what it's waiting for
+
+	replay     *Replay            // the inputs of the run so far, nil for network games
+	finalState string             // stateHash of where the last run ended, set by endRun
+	headless   bool               // playing a replay back to verify it, nothing is saved or sent
+	online     *onlineLeaderboard // nil unless a leaderboard URL is set
+
+	events       eventBus // what's happening in the simulation, for anything that wants to react to it
+	achievements Achievements
+	stats        RunStats // the run in progress, or the one that just ended
+	popups       ScorePopups
 }
 
 type Powerup struct {
 	X, Y   float64
+	VX, VY float64
 	Type   int
 	Active bool
+	Life   int // frames until it disappears
 }
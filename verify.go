@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// runResult is how a solo run ended, what a leaderboard submission claims
+// and what verifyReplay works out for itself.
+type runResult struct {
+	Board     string `json:"board"`
+	Seed1     uint64 `json:"seed1"`
+	Seed2     uint64 `json:"seed2"`
+	Score     int    `json:"score"`
+	Frames    int    `json:"frames"`
+	StateHash string `json:"stateHash"` // checksum of the state the run ended in
+}
+
+// stateHash is what endRun records in finalState.
+func (g *Game) stateHash() string {
+	return fmt.Sprintf("%016x", g.checksum())
+}
+
+// verifyReplay plays a replay back with no window or sound and checks it
+// ends the way claim says. Zero fields in claim aren't checked. The result is
+// what the replay actually did, whether or not it matched. It's in package
+// main with the rest of the simulation, so a server reaches it through
+// verifyCommand rather than importing it.
+func verifyReplay(data []byte, claim runResult) (runResult, error) {
+	var r Replay
+	if err := json.Unmarshal(data, &r); err != nil {
+		return runResult{}, fmt.Errorf("parsing replay: %w", err)
+	}
+	if r.Version != replayVersion {
+		return runResult{}, fmt.Errorf("replay is version %d, this build plays version %d", r.Version, replayVersion)
+	}
+	// only solo survival runs go on the leaderboard
+	if r.Config.Mode != modeSurvival || r.Config.Players != 1 {
+		return runResult{}, errors.New("not a solo run")
+	}
+	if r.Config.Daily != "" {
+		if err := checkDaily(r.Config); err != nil {
+			return runResult{}, err
+		}
+		if r.Config.Practice {
+			return runResult{}, errors.New("daily practice runs aren't scored")
+		}
+	}
+	if len(r.Inputs) == 0 || len(r.Inputs)%r.Config.Players != 0 {
+		return runResult{}, errors.New("replay inputs are truncated")
+	}
+
+	g := &Game{headless: true}
+	g.settings = defaultSettings()
+	g.newRun(r.Config)
+
+	frames := r.Frames()
+	for i := 0; i < frames; i++ {
+		g.step(r.frame(i))
+		if g.finalState != "" && i != frames-1 {
+			return runResult{}, fmt.Errorf("run ended on frame %d but the replay goes on to %d", i+1, frames)
+		}
+	}
+	if g.finalState == "" {
+		return runResult{}, fmt.Errorf("run was still going after all %d frames", frames)
+	}
+
+	got := runResult{
+		Board:     runBoard(r.Config),
+		Seed1:     r.Config.Seed1,
+		Seed2:     r.Config.Seed2,
+		Score:     g.previousScores[0],
+		Frames:    frames,
+		StateHash: g.finalState,
+	}
+	switch {
+	case claim.Board != "" && claim.Board != got.Board:
+		return got, fmt.Errorf("played on %s, not %s", got.Board, claim.Board)
+	case (claim.Seed1 != 0 || claim.Seed2 != 0) && (claim.Seed1 != got.Seed1 || claim.Seed2 != got.Seed2):
+		return got, errors.New("seed doesn't match the replay")
+	case claim.Score != 0 && claim.Score != got.Score:
+		return got, fmt.Errorf("replay scores %d, not %d", got.Score, claim.Score)
+	case claim.Frames != 0 && claim.Frames != got.Frames:
+		return got, fmt.Errorf("replay lasts %d frames, not %d", got.Frames, claim.Frames)
+	case claim.StateHash != "" && claim.StateHash != got.StateHash:
+		return got, errors.New("replay ends in a different state, it has desynced or been tampered with")
+	}
+	return got, nil
+}
+
+// verifyCommand is `space-shooter verify [flags] replay.json`, which checks a
+// replay without opening a window. It prints what the replay did as JSON and
+// exits 1 if it doesn't match the claim. A replay of "-" is read from stdin,
+// which is how leaderboard.CommandVerifier runs it.
+func verifyCommand(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	var claim runResult
+	fs.StringVar(&claim.Board, "board", "", "the board the run was submitted to")
+	fs.Uint64Var(&claim.Seed1, "seed1", 0, "the run's claimed seed")
+	fs.Uint64Var(&claim.Seed2, "seed2", 0, "the run's claimed seed")
+	fs.IntVar(&claim.Score, "score", 0, "the claimed score")
+	fs.IntVar(&claim.Frames, "frames", 0, "the claimed length of the run in frames")
+	fs.StringVar(&claim.StateHash, "state", "", "the claimed final state hash")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: space-shooter verify [flags] replay.json")
+		fs.PrintDefaults()
+		return 2
+	}
+
+	var data []byte
+	var err error
+	if name := fs.Arg(0); name == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(name)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	got, err := verifyReplay(data, claim)
+	if got.Frames > 0 {
+		out, _ := json.Marshal(got)
+		fmt.Println(string(out))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
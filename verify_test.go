@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// recordRun plays a solo run to the end with scripted inputs and returns
+// its replay along with how it ended.
+func recordRun(t *testing.T) (*Replay, runResult) {
+	t.Helper()
+	g := &Game{headless: true, settings: defaultSettings()}
+	cfg := g.newRunConfig(modeSurvival, 1)
+	cfg.Seed1, cfg.Seed2 = 5, 6
+	g.newRun(cfg)
+	g.players[0].Lives = 0
+
+	inputs := scriptedInputs(20000)
+	for i := 0; g.finalState == ""; i++ {
+		if i == len(inputs) {
+			t.Fatalf("the run was still going after %d frames", i)
+		}
+		g.step([]PlayerInput{inputs[i]})
+	}
+	return g.replay, runResult{
+		Board:     runBoard(cfg),
+		Seed1:     cfg.Seed1,
+		Seed2:     cfg.Seed2,
+		Score:     g.previousScores[0],
+		Frames:    g.replay.Frames(),
+		StateHash: g.finalState,
+	}
+}
+
+func encodeReplay(t *testing.T, r Replay) []byte {
+	t.Helper()
+	data, err := r.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestVerifyReplay(t *testing.T) {
+	replay, claim := recordRun(t)
+	if claim.Frames < 10 {
+		t.Fatalf("the run only lasted %d frames", claim.Frames)
+	}
+
+	got, err := verifyReplay(encodeReplay(t, *replay), claim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != claim {
+		t.Fatalf("played back as %+v, recorded %+v", got, claim)
+	}
+
+	withInputs := func(inputs []byte) Replay {
+		r := *replay
+		r.Inputs = inputs
+		return r
+	}
+	flipped := append([]byte(nil), replay.Inputs...)
+	flipped[len(flipped)/2] ^= 1 << 4 // let go of fire for a frame
+
+	tests := []struct {
+		name   string
+		replay Replay
+		claim  func(c runResult) runResult
+		want   string // part of the error
+	}{
+		{"flipped input", withInputs(flipped), nil, "different state"},
+		{"inflated score", *replay, func(c runResult) runResult { c.Score += 1000; return c }, "replay scores"},
+		{"wrong frames", *replay, func(c runResult) runResult { c.Frames++; return c }, "replay lasts"},
+		{"wrong state", *replay, func(c runResult) runResult { c.StateHash = "0123456789abcdef"; return c }, "different state"},
+		{"truncated", withInputs(replay.Inputs[:len(replay.Inputs)-5]), nil, "still going"},
+		{"runs on after dying", withInputs(append(append([]byte(nil), replay.Inputs...), 0, 0, 0)), nil, "but the replay goes on"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := claim
+			if tt.claim != nil {
+				c = tt.claim(c)
+			}
+			_, err := verifyReplay(encodeReplay(t, tt.replay), c)
+			if err == nil {
+				t.Fatal("it verified")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got %q, want it to say %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/font/basicfont"
+)
+
+type GameMode int
+
+const (
+	modeSurvival GameMode = iota // solo and co-op, everyone against the enemies
+	modeVersus
+)
+
+const (
+	versusKillsPerRound = 3
+	versusRoundsToWin   = 2 // best of three
+	versusIntroFrames   = 120
+	respawnFrames       = 120
+	respawnShieldFrames = 120
+
+	// the safe zone starts bigger than the screen and closes in on the
+	// middle over a minute, so nobody can hide in a corner
+	zoneStartRadius  = 820.0
+	zoneEndRadius    = 140.0
+	zoneShrinkFrames = 60 * 60
+	zoneGraceFrames  = 90 // how long a ship can be outside the zone before it's destroyed
+	zoneX, zoneY     = 640.0, 480.0
+)
+
+// Versus is the state of a versus match, it's part of the simulation so
+// it's saved and sent over the network along with everything else.
+type Versus struct {
+	Round        int             `json:"round"` // counting from 1
+	RoundFrame   int             `json:"roundFrame"`
+	Intro        int             `json:"intro"` // frames left of the round announcement, nobody moves until it's done
+	Kills        [maxPlayers]int `json:"kills"` // this round
+	TotalKills   [maxPlayers]int `json:"totalKills"`
+	Deaths       [maxPlayers]int `json:"deaths"`
+	RoundWins    [maxPlayers]int `json:"roundWins"`
+	RoundWinners []int           `json:"roundWinners"`
+	Respawn      [maxPlayers]int `json:"respawn"` // frames until a dead ship comes back
+	Outside      [maxPlayers]int `json:"outside"` // frames each ship has been outside the zone
+	Winner       int             `json:"winner"`  // -1 until the match is over
+}
+
+func newVersus() Versus {
+	return Versus{Round: 1, Intro: versusIntroFrames, Winner: -1}
+}
+
+// zoneRadius shrinks linearly through the round and then holds.
+func (v *Versus) zoneRadius() float64 {
+	t := math.Min(1, float64(v.RoundFrame)/zoneShrinkFrames)
+	return zoneStartRadius + (zoneEndRadius-zoneStartRadius)*t
+}
+
+// placeVersusShip puts a ship back on its own side of the zone, facing the other one.
+func (g *Game) placeVersusShip(p *Player) {
+	offset := 0.6 * g.versus.zoneRadius()
+	angle := math.Pi / 2 // facing right
+	if p.ID == 1 {
+		offset = -offset
+		angle = -math.Pi / 2
+	}
+	*p = Player{
+		ID:            p.ID,
+		Location:      Point{X: int(zoneX - offset), Y: int(zoneY)},
+		ShipAngle:     angle,
+		MaxSpeed:      p.MaxSpeed,
+		ShootCooldown: bulletCooldown,
+		Score:         p.Score,
+		Alive:         true,
+	}
+	p.Effects.add(effectShield, respawnShieldFrames)
+}
+
+func (g *Game) stepVersus(inputs []PlayerInput) {
+	v := &g.versus
+	if v.Winner >= 0 {
+		return
+	}
+
+	if g.flashTimer > 0 {
+		g.flashTimer--
+	}
+	if v.Intro > 0 {
+		v.Intro--
+		return
+	}
+	v.RoundFrame++
+
+	g.tickEffects()
+	for _, p := range g.players {
+		if !p.Alive {
+			v.Respawn[p.ID]--
+			if v.Respawn[p.ID] <= 0 {
+				g.placeVersusShip(p)
+			}
+			continue
+		}
+		p.tickTimers()
+		g.HandleKeyPresses(p, inputs[p.ID])
+		movePlayerShip(g, p)
+	}
+	g.updateShockwaves()
+	handleShooting(g)
+
+	// same ship polygon hit test as enemies use, any bullet that isn't your own can hit you
+	for _, b := range g.bullets {
+		if !b.Active {
+			continue
+		}
+		for _, p := range g.players {
+			if !bulletHitsPlayer(b, p) {
+				continue
+			}
+			if result := g.damagePlayer(p, Damage{Cause: diedToBullet, Bullet: b}); result != damageIgnored {
+				b.Active = false
+				if result == damageFatal {
+					g.versusKill(p, b)
+				}
+				break
+			}
+		}
+		if v.Winner >= 0 || v.Intro > 0 {
+			return // the kill ended the round
+		}
+	}
+
+	radius := g.versus.zoneRadius()
+	for _, p := range g.players {
+		if !p.Alive {
+			continue
+		}
+		dx := float64(p.Location.X) - zoneX
+		dy := float64(p.Location.Y) - zoneY
+		if dx*dx+dy*dy <= radius*radius {
+			v.Outside[p.ID] = 0
+			continue
+		}
+		v.Outside[p.ID]++
+		if v.Outside[p.ID] > zoneGraceFrames && g.damagePlayer(p, Damage{Cause: diedToZone}) == damageFatal {
+			g.versusKill(p, nil)
+			if v.Winner >= 0 || v.Intro > 0 {
+				return
+			}
+		}
+	}
+}
+
+// versusKill scores victim having been destroyed, shot by b or with b nil
+// for the zone. Dying to the zone still gives the other player the point or
+// there'd be no reason to push them out.
+func (g *Game) versusKill(victim *Player, b *Bullet) {
+	v := &g.versus
+	killer := -1
+	if b != nil {
+		killer = b.Owner
+	}
+	v.Deaths[victim.ID]++
+	v.Respawn[victim.ID] = respawnFrames
+	v.Outside[victim.ID] = 0
+
+	if killer < 0 || killer == victim.ID {
+		killer = 1 - victim.ID
+	}
+	v.Kills[killer]++
+	v.TotalKills[killer]++
+	g.players[killer].Score++
+
+	if v.Kills[killer] >= versusKillsPerRound {
+		g.endVersusRound(killer)
+	}
+}
+
+func (g *Game) endVersusRound(winner int) {
+	v := &g.versus
+	v.RoundWins[winner]++
+	v.RoundWinners = append(v.RoundWinners, winner)
+
+	if v.RoundWins[winner] >= versusRoundsToWin {
+		v.Winner = winner
+		summary := *v
+		g.lastMatch = &summary
+		g.endRun()
+		g.Reset()
+		return
+	}
+
+	v.Round++
+	v.RoundFrame = 0
+	v.Intro = versusIntroFrames
+	v.Kills = [maxPlayers]int{}
+	v.Respawn = [maxPlayers]int{}
+	v.Outside = [maxPlayers]int{}
+	g.bullets = g.bullets[:0]
+	for _, p := range g.players {
+		g.placeVersusShip(p)
+	}
+}
+
+// drawVersusZone shades everything outside the safe zone. The edge is
+// always drawn as a ring as well so it doesn't rely on the tint.
+func (g *Game) drawVersusZone(screen *ebiten.Image) {
+	palette := g.settings.Accessibility.palette()
+	radius := g.versus.zoneRadius()
+	tint := palette.Anomaly
+	drawOverlayWithHoles(screen, color.RGBA{tint.R / 4, tint.G / 4, tint.B / 4, 70}, SafeZone{X: zoneX, Y: zoneY, Radius: radius})
+	vector.StrokeCircle(screen, zoneX, zoneY, float32(radius), palette.StrokeWidth, palette.Warning, true)
+}
+
+func (g *Game) drawVersusHUD(screen *ebiten.Image) {
+	v := &g.versus
+	palette := g.settings.Accessibility.palette()
+	face := basicfont.Face7x13
+	w := screen.Bounds().Dx()
+
+	for _, p := range g.players {
+		lines := []string{
+			"P" + strconv.Itoa(p.ID+1) + " Kills: " + strconv.Itoa(v.Kills[p.ID]) + "/" + strconv.Itoa(versusKillsPerRound),
+			"Rounds: " + strconv.Itoa(v.RoundWins[p.ID]),
+		}
+		if !p.Alive {
+			lines = append(lines, "Respawning...")
+		} else if v.Outside[p.ID] > 0 {
+			lines = append(lines, "GET BACK IN THE ZONE!")
+		}
+		for i, line := range lines {
+			x := 10
+			if p.ID == 1 {
+				x = w - text.BoundString(face, line).Dx() - 10
+			}
+			text.Draw(screen, line, face, x, 20+20*i, palette.shipColour(p.ID))
+		}
+	}
+
+	round := "ROUND " + strconv.Itoa(v.Round)
+	text.Draw(screen, round, face, (w-text.BoundString(face, round).Dx())/2, 20, color.White)
+
+	if v.Intro > 0 {
+		bounds := text.BoundString(bigFont, round)
+		text.Draw(screen, round, bigFont, (w-bounds.Dx())/2, 300, palette.Warning)
+	}
+}
+
+// versusSummary is the match summary shown on the title screen afterwards.
+func versusSummary(v *Versus) []string {
+	lines := []string{
+		fmt.Sprintf("PLAYER %d WINS", v.Winner+1),
+		"",
+	}
+	for i, winner := range v.RoundWinners {
+		lines = append(lines, fmt.Sprintf("Round %d: Player %d", i+1, winner+1))
+	}
+	lines = append(lines, "")
+	for p := 0; p < maxPlayers; p++ {
+		lines = append(lines, fmt.Sprintf("Player %d - rounds %d, kills %d, deaths %d", p+1, v.RoundWins[p], v.TotalKills[p], v.Deaths[p]))
+	}
+	return lines
+}